@@ -12,7 +12,6 @@ require (
 
 require (
 	fyne.io/systray v1.10.1-0.20221115204952-d16a6177e6f1 // indirect
-	github.com/Knetic/govaluate v3.0.0+incompatible // indirect
 	github.com/benoitkugler/textlayout v0.3.0 // indirect
 	github.com/cilium/ebpf v0.7.0 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
@@ -0,0 +1,338 @@
+package UI
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/go-delve/delve/service/api"
+	"strconv"
+	"strings"
+)
+
+// MemoryOperand 表示指令中的内存操作数，例如 qword ptr [rbx+rcx*8+0x18]
+type MemoryOperand struct {
+	// Size 是访问的字节数，lea 之类没有标注大小的为 0
+	Size int
+	// Segment 是段前缀，只有 fs 和 gs 有意义
+	Segment string
+	// Symbol 是反汇编时已经被解析成符号的地址，例如 [main.counter+8]
+	Symbol string
+	Base   string
+	Index  string
+	Scale  uint64
+	Disp   int64
+}
+
+// RFLAGS 中各个标志位的位置
+const (
+	flagCF = 1 << 0
+	flagPF = 1 << 2
+	flagZF = 1 << 6
+	flagSF = 1 << 7
+	flagOF = 1 << 11
+)
+
+// ParseMemoryOperand 从 Intel 语法的汇编指令中解析出内存操作数，没有内存操作数时返回 false
+func ParseMemoryOperand(text string) (*MemoryOperand, bool) {
+	left := strings.Index(text, "[")
+	right := strings.LastIndex(text, "]")
+	if left == -1 || right < left {
+		return nil, false
+	}
+	operand := new(MemoryOperand)
+	prefix := text[:left]
+	if ptr := strings.LastIndex(prefix, " ptr "); ptr != -1 {
+		fields := strings.Fields(prefix[:ptr])
+		if len(fields) > 0 {
+			operand.Size = operandSize(fields[len(fields)-1])
+		}
+		prefix = prefix[ptr+len(" ptr "):]
+	}
+	if strings.HasSuffix(prefix, ":") {
+		operand.Segment = strings.TrimSuffix(prefix, ":")
+	}
+
+	inner := text[left+1 : right]
+	for len(inner) > 0 {
+		// 每一项带着前面的符号
+		sign := int64(1)
+		if inner[0] == '+' || inner[0] == '-' {
+			if inner[0] == '-' {
+				sign = -1
+			}
+			inner = inner[1:]
+		}
+		end := strings.IndexAny(inner, "+-")
+		term := inner
+		if end != -1 {
+			term = inner[:end]
+			inner = inner[end:]
+		} else {
+			inner = ""
+		}
+		if term == "" {
+			return nil, false
+		}
+		if star := strings.Index(term, "*"); star != -1 {
+			scale, err := strconv.ParseUint(term[star+1:], 0, 64)
+			if err != nil {
+				return nil, false
+			}
+			operand.Index = term[:star]
+			operand.Scale = scale
+		} else if term[0] >= '0' && term[0] <= '9' {
+			disp, err := strconv.ParseUint(term, 0, 64)
+			if err != nil {
+				return nil, false
+			}
+			operand.Disp += sign * int64(disp)
+		} else if isRegisterName(term) {
+			operand.Base = term
+		} else {
+			operand.Symbol = term
+		}
+	}
+	return operand, true
+}
+
+// operandSize 根据 byte/word/dword/qword 等关键字得到访问的字节数
+func operandSize(keyword string) int {
+	switch keyword {
+	case "byte":
+		return 1
+	case "word":
+		return 2
+	case "dword":
+		return 4
+	case "qword":
+		return 8
+	case "xmmword":
+		return 16
+	case "ymmword":
+		return 32
+	case "zmmword":
+		return 64
+	}
+	return 0
+}
+
+// subRegister 表示 64 位寄存器的一部分，例如 eax 是 rax 的低 32 位
+type subRegister struct {
+	full  string
+	shift uint
+	bits  uint
+}
+
+// subRegisters 是 32/16/8 位寄存器到 64 位寄存器的映射
+var subRegisters = func() map[string]subRegister {
+	result := make(map[string]subRegister)
+	for _, r := range []string{"ax", "bx", "cx", "dx"} {
+		full := "r" + r
+		result["e"+r] = subRegister{full, 0, 32}
+		result[r] = subRegister{full, 0, 16}
+		result[r[:1]+"l"] = subRegister{full, 0, 8}
+		result[r[:1]+"h"] = subRegister{full, 8, 8}
+	}
+	for _, r := range []string{"si", "di", "bp", "sp"} {
+		full := "r" + r
+		result["e"+r] = subRegister{full, 0, 32}
+		result[r] = subRegister{full, 0, 16}
+		result[r+"l"] = subRegister{full, 0, 8}
+	}
+	for i := 8; i <= 15; i++ {
+		full := fmt.Sprintf("r%d", i)
+		result[full+"d"] = subRegister{full, 0, 32}
+		result[full+"w"] = subRegister{full, 0, 16}
+		result[full+"b"] = subRegister{full, 0, 8}
+		result[full+"l"] = subRegister{full, 0, 8}
+	}
+	result["eip"] = subRegister{"rip", 0, 32}
+	return result
+}()
+
+// isRegisterName 判断是不是通用寄存器的名字
+func isRegisterName(name string) bool {
+	if _, ok := subRegisters[name]; ok {
+		return true
+	}
+	for _, sub := range subRegisters {
+		if sub.full == name {
+			return true
+		}
+	}
+	return false
+}
+
+// registerValue 取得寄存器的值，支持 eax、r8d、al 这类寄存器的一部分
+func registerValue(name string) (uint64, error) {
+	return frameRegisterValue(0, name)
+}
+
+// frameRegisterValue 取得第 frame 帧中寄存器的值，支持寄存器的一部分
+func frameRegisterValue(frame int, name string) (uint64, error) {
+	name = strings.ToLower(name)
+	if sub, ok := subRegisters[name]; ok {
+		value, err := client.FrameRegisterValue(frame, sub.full)
+		if err != nil {
+			return 0, err
+		}
+		return (value >> sub.shift) & (1<<sub.bits - 1), nil
+	}
+	return client.FrameRegisterValue(frame, name)
+}
+
+// EffectiveAddress 根据当前的寄存器计算内存操作数的有效地址
+// nextPC 是下一条指令的地址，用于计算 rip 相对寻址
+func (operand *MemoryOperand) EffectiveAddress(nextPC uint64) (uint64, error) {
+	var address uint64
+	if operand.Symbol != "" {
+		symbol, err := client.SymbolAddress(operand.Symbol)
+		if err != nil {
+			return 0, err
+		}
+		address = symbol
+	}
+	if operand.Base == "rip" {
+		address += nextPC
+	} else if operand.Base != "" {
+		base, err := registerValue(operand.Base)
+		if err != nil {
+			return 0, err
+		}
+		address += base
+	}
+	if operand.Index != "" {
+		index, err := registerValue(operand.Index)
+		if err != nil {
+			return 0, err
+		}
+		address += index * operand.Scale
+	}
+	address += uint64(operand.Disp)
+	switch operand.Segment {
+	case "fs", "gs":
+		base, err := client.RegisterValue(operand.Segment + "_base")
+		if err != nil {
+			return 0, err
+		}
+		address += base
+	}
+	return address, nil
+}
+
+// FormatOperandValue 把读取到的内存按小端序格式化，超过 8 字节的按字节输出
+func FormatOperandValue(data []byte, size int) string {
+	switch size {
+	case 1:
+		return fmt.Sprintf("0x%x", data[0])
+	case 2:
+		return fmt.Sprintf("0x%x", binary.LittleEndian.Uint16(data))
+	case 4:
+		return fmt.Sprintf("0x%x", binary.LittleEndian.Uint32(data))
+	case 8:
+		return fmt.Sprintf("0x%x", binary.LittleEndian.Uint64(data))
+	}
+	sb := strings.Builder{}
+	sb.WriteString("0x")
+	for i := size - 1; i >= 0; i-- {
+		sb.WriteString(fmt.Sprintf("%02x", data[i]))
+	}
+	return sb.String()
+}
+
+// JumpTaken 根据 RFLAGS（以及 rcx）判断条件跳转是否会发生，不是条件跳转时第二个返回值为 false
+func JumpTaken(mnemonic string, rflags, rcx uint64) (bool, bool) {
+	cf := rflags&flagCF != 0
+	pf := rflags&flagPF != 0
+	zf := rflags&flagZF != 0
+	sf := rflags&flagSF != 0
+	of := rflags&flagOF != 0
+	// 去掉分支预测的提示，例如 jbe.pn
+	mnemonic = strings.SplitN(mnemonic, ".", 2)[0]
+	switch mnemonic {
+	case "jo":
+		return of, true
+	case "jno":
+		return !of, true
+	case "jb", "jc", "jnae":
+		return cf, true
+	case "jae", "jnb", "jnc":
+		return !cf, true
+	case "je", "jz":
+		return zf, true
+	case "jne", "jnz":
+		return !zf, true
+	case "jbe", "jna":
+		return cf || zf, true
+	case "ja", "jnbe":
+		return !cf && !zf, true
+	case "js":
+		return sf, true
+	case "jns":
+		return !sf, true
+	case "jp", "jpe":
+		return pf, true
+	case "jnp", "jpo":
+		return !pf, true
+	case "jl", "jnge":
+		return sf != of, true
+	case "jge", "jnl":
+		return sf == of, true
+	case "jle", "jng":
+		return zf || sf != of, true
+	case "jg", "jnle":
+		return !zf && sf == of, true
+	case "jrcxz":
+		return rcx == 0, true
+	case "jecxz":
+		return uint32(rcx) == 0, true
+	case "jcxz":
+		return uint16(rcx) == 0, true
+	}
+	return false, false
+}
+
+// annotateInstruction 为当前 Rip 所指的指令生成注释
+// 内存操作数显示有效地址和其中的值，条件跳转显示是否会跳转
+func annotateInstruction(asm api.AsmInstruction) string {
+	if client == nil {
+		return ""
+	}
+	fields := strings.Fields(asm.Text)
+	if len(fields) == 0 {
+		return ""
+	}
+	mnemonic := fields[0]
+
+	if strings.HasPrefix(mnemonic, "j") {
+		rflags, err := client.RegisterValue("Rflags")
+		if err != nil {
+			return ""
+		}
+		rcx, _ := client.RegisterValue("Rcx")
+		if taken, ok := JumpTaken(mnemonic, rflags, rcx); ok {
+			if taken {
+				return "; jump taken"
+			}
+			return "; jump not taken"
+		}
+		return ""
+	}
+
+	operand, ok := ParseMemoryOperand(asm.Text)
+	if !ok {
+		return ""
+	}
+	address, err := operand.EffectiveAddress(asm.Loc.PC + uint64(len(asm.Bytes)))
+	if err != nil {
+		return ""
+	}
+	// lea 只计算地址，不访问内存
+	if mnemonic == "lea" || operand.Size == 0 {
+		return fmt.Sprintf("; 0x%x", address)
+	}
+	data, err := client.ExamineMemory(address, operand.Size)
+	if err != nil {
+		return fmt.Sprintf("; [0x%x] = ??", address)
+	}
+	return fmt.Sprintf("; [0x%x] = %s", address, FormatOperandValue(data, operand.Size))
+}
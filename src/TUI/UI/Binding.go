@@ -0,0 +1,88 @@
+package UI
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Binding 是监视器和跟踪器保存的地址表达式，每次停下时重新计算地址
+// Scope 为空表示全局，在第 0 帧中计算，否则是函数名，只有该函数在调用栈中时才有效，并且在该函数的帧中计算
+type Binding struct {
+	Expr  string
+	Scope string
+}
+
+// String 是显示的名字，函数内的表达式带上 @函数名
+func (b Binding) String() string {
+	if b.Scope == "" {
+		return b.Expr
+	}
+	return b.Expr + " @" + b.Scope
+}
+
+// Resolve 计算表达式的地址，函数不在调用栈中时返回 false
+func (b Binding) Resolve() (uint64, bool, error) {
+	if b.Scope == "" {
+		// 全局的表达式总是在第 0 帧中计算，寄存器和变量来自同一帧，不受 frame 命令的影响
+		address, err := EvaluateExpression(b.Expr, frameEnv{0})
+		return address, true, err
+	}
+	frame, ok, err := client.FindFrame(b.Scope)
+	if err != nil || !ok {
+		return 0, false, err
+	}
+	address, err := EvaluateExpression(b.Expr, frameEnv{frame})
+	return address, true, err
+}
+
+// ParseBinding 从命令的参数中解析出表达式和作用域，返回剩下的参数
+// --global 表示全局，--in <function> 指定函数，都没有时用到寄存器或局部变量的表达式属于当前函数
+func ParseBinding(args []string, currentFunction string) (Binding, []string, error) {
+	rest := make([]string, 0, len(args))
+	var binding Binding
+	scopeSet := false
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--global":
+			binding.Scope = ""
+			scopeSet = true
+		case "--in":
+			if i+1 >= len(args) {
+				return binding, nil, fmt.Errorf("--in needs a function name")
+			}
+			i++
+			binding.Scope = args[i]
+			scopeSet = true
+		default:
+			rest = append(rest, args[i])
+		}
+	}
+	if len(rest) == 0 {
+		return binding, nil, fmt.Errorf("missing expression")
+	}
+	binding.Expr = rest[0]
+	if _, err := tokenize(binding.Expr); err != nil {
+		return binding, nil, err
+	}
+	if !scopeSet && UsesFrame(binding.Expr) {
+		binding.Scope = currentFunction
+	}
+	return binding, rest[1:], nil
+}
+
+// resolveValue 计算地址并按照显示类型读取数据，返回的 status 不为空时表示没有读到数据的原因
+func resolveValue(binding Binding, display DisplayType) (uint64, string, string) {
+	address, inScope, err := binding.Resolve()
+	if err != nil {
+		// 表达式的错误有多行，只显示第一行
+		return 0, "", "error: " + strings.SplitN(err.Error(), "\n", 2)[0]
+	}
+	if !inScope {
+		return 0, "", "out of scope"
+	}
+	data, err := display.readValue(address)
+	if err != nil {
+		return address, "", "error: " + err.Error()
+	}
+	return address, data, ""
+}
@@ -0,0 +1,127 @@
+package UI
+
+import (
+	"fmt"
+	"github.com/rivo/tview"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DisplayType 是监视器和跟踪器显示数据的方式
+type DisplayType struct {
+	// Name 是类型名，例如 hex、i32、f64、str、ptr，Go 类型时是 Go 的类型名
+	Name string
+	// Size 是读取的字节数，Go 类型时为 0，由 delve 读取
+	Size int
+	// isGoType 表示通过 delve 按照 Go 类型读取
+	isGoType bool
+}
+
+// displayTypeSizes 是固定大小的显示类型
+var displayTypeSizes = map[string]int{
+	"i8": 1, "i16": 2, "i32": 4, "i64": 8,
+	"u8": 1, "u16": 2, "u32": 4, "u64": 8,
+	"f32": 4, "f64": 8,
+	"bool": 1, "ptr": 8,
+}
+
+// defaultStringLength 是 str 没有指定长度时读取的字节数
+const defaultStringLength = 32
+
+// ParseDisplayType 解析显示类型
+// 数字表示按照十六进制显示这么多字节，i8-i64/u8-u64 是整数，f32/f64 是浮点数，bool，ptr 是指针（显示符号），
+// str 或 str:N 是定长字符串，其他的当作 Go 类型，例如 main.T、*net/http.Request、[]int
+func ParseDisplayType(s string) (DisplayType, error) {
+	if size, err := strconv.Atoi(s); err == nil {
+		if size <= 0 || size > 64 {
+			return DisplayType{}, fmt.Errorf("invalid size %d", size)
+		}
+		return DisplayType{Name: "hex", Size: size}, nil
+	}
+	name := strings.ToLower(s)
+	if size, ok := displayTypeSizes[name]; ok {
+		return DisplayType{Name: name, Size: size}, nil
+	}
+	if name == "str" || strings.HasPrefix(name, "str:") {
+		size := defaultStringLength
+		if name != "str" {
+			n, err := strconv.Atoi(name[len("str:"):])
+			if err != nil || n <= 0 || n > maxStringLength*8 {
+				return DisplayType{}, fmt.Errorf("invalid string length in %s", s)
+			}
+			size = n
+		}
+		return DisplayType{Name: "str", Size: size}, nil
+	}
+	if strings.ContainsAny(s, ".*[") {
+		return DisplayType{Name: s, isGoType: true}, nil
+	}
+	return DisplayType{}, fmt.Errorf("unknown display type %s", s)
+}
+
+// String 是显示的类型名
+func (t DisplayType) String() string {
+	switch t.Name {
+	case "hex":
+		return fmt.Sprintf("hex%d", t.Size)
+	case "str":
+		return fmt.Sprintf("str:%d", t.Size)
+	}
+	return t.Name
+}
+
+// FormatValue 按照显示类型格式化读取到的小端序数据，不包括 Go 类型
+func (t DisplayType) FormatValue(data []byte) string {
+	if len(data) > t.Size {
+		data = data[:t.Size]
+	}
+	switch t.Name {
+	case "str":
+		end := 0
+		for end < len(data) && data[end] != 0 {
+			end++
+		}
+		return tview.Escape(strconv.Quote(string(data[:end])))
+	case "bool":
+		return strconv.FormatBool(data[0] != 0)
+	}
+	if len(data) > 8 {
+		// 超过 8 个字节的按照内存中的顺序显示每个字节
+		return fmt.Sprintf("% x", data)
+	}
+	value := littleEndianValue(data)
+	bits := uint(len(data) * 8)
+	switch t.Name {
+	case "i8", "i16", "i32", "i64":
+		return strconv.FormatInt(int64(value<<(64-bits))>>(64-bits), 10)
+	case "u8", "u16", "u32", "u64":
+		return strconv.FormatUint(value, 10)
+	case "f32":
+		return strconv.FormatFloat(float64(math.Float32frombits(uint32(value))), 'g', -1, 32)
+	case "f64":
+		return strconv.FormatFloat(math.Float64frombits(value), 'g', -1, 64)
+	case "ptr":
+		if symbol := symbolize(value); symbol != "" {
+			return fmt.Sprintf("0x%x <%s>", value, symbol)
+		}
+		return fmt.Sprintf("0x%x", value)
+	}
+	return fmt.Sprintf("0x%0*x", len(data)*2, value)
+}
+
+// readValue 读取 address 处的数据，并按照显示类型格式化
+func (t DisplayType) readValue(address uint64) (string, error) {
+	if t.isGoType {
+		variable, err := client.EvalAddressAs(address, t.Name)
+		if err != nil {
+			return "", err
+		}
+		return variableValue(*variable), nil
+	}
+	data, err := client.ExamineMemory(address, t.Size)
+	if err != nil {
+		return "", err
+	}
+	return t.FormatValue(data), nil
+}
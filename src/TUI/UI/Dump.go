@@ -0,0 +1,75 @@
+package UI
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// DumpFormats 是 dump-memory 支持的输出格式
+// raw 是原始的字节，hex 是和 xxd 类似的十六进制输出，go 是 Go 的 []byte 字面量
+var DumpFormats = []string{"raw", "hex", "go"}
+
+// EncodeMemoryDump 按照 format 把内存的数据转换成写入文件的内容
+func EncodeMemoryDump(data []byte, start uint64, format string) ([]byte, error) {
+	switch format {
+	case "raw":
+		return data, nil
+	case "hex":
+		return []byte(hex.Dump(data)), nil
+	case "go":
+		sb := strings.Builder{}
+		sb.WriteString(fmt.Sprintf("// 0x%x, %d bytes\n", start, len(data)))
+		sb.WriteString("[]byte{\n")
+		for offset := 0; offset < len(data); offset += 16 {
+			end := offset + 16
+			if end > len(data) {
+				end = len(data)
+			}
+			values := make([]string, 0, end-offset)
+			for _, b := range data[offset:end] {
+				values = append(values, fmt.Sprintf("0x%02x", b))
+			}
+			sb.WriteString("\t" + strings.Join(values, ", ") + ",\n")
+		}
+		sb.WriteString("}\n")
+		return []byte(sb.String()), nil
+	}
+	return nil, fmt.Errorf("unknown dump format %s, available: %s", format, strings.Join(DumpFormats, "/"))
+}
+
+// maxDumpBytes 是一次最多导出的字节数，delve 一次只能读取不到 1000 个字节，太大的范围要很多次 rpc
+const maxDumpBytes = 0x1000000
+
+// dumpMemory 把从 address 开始的 size 个字节按照 format 写入文件
+func dumpMemory(address uint64, size int, filename, format string) error {
+	if size <= 0 || size > maxDumpBytes {
+		return fmt.Errorf("invalid dump size 0x%x, at most 0x%x", size, maxDumpBytes)
+	}
+	data, err := client.ReadMemory(address, size)
+	if err != nil {
+		return err
+	}
+	content, err := EncodeMemoryDump(data, address, format)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, content, 0644)
+}
+
+// loadMemory 把文件的内容原样写入从 address 开始的内存，返回写入的字节数
+func loadMemory(filename string, address uint64) (int, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return 0, err
+	}
+	if len(data) > maxExamineBytes {
+		return 0, fmt.Errorf("file %s is too large: 0x%x bytes, at most 0x%x", filename, len(data), maxExamineBytes)
+	}
+	// 先逐段确认这段内存是可以访问的，错误中有第一个不能访问的地址
+	if _, err = client.ReadMemory(address, len(data)); err != nil {
+		return 0, err
+	}
+	return len(data), client.WriteMemory(address, data)
+}
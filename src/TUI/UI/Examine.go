@@ -0,0 +1,248 @@
+package UI
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/rivo/tview"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// maxExamineBytes 是 x 命令一次最多读取的字节数，超过 delve 单次读取限制的部分由 client 分段读取
+const maxExamineBytes = 0x10000
+
+// defaultExamineBytes 没有指定 count 时，数值格式显示这么多字节
+const defaultExamineBytes = 0x80
+
+// defaultExamineInstructions 没有指定 count 时，i 格式显示的指令条数
+const defaultExamineInstructions = 8
+
+// ExamineFormats 是 x 命令支持的格式
+// x 十六进制，d 有符号十进制，u 无符号十进制，o 八进制，t 二进制，c 字符，f 浮点数，
+// a 地址（显示指向的内容），s 字符串，i 指令
+const ExamineFormats = "xduotcfasi"
+
+// ExamineSpec 是 x 命令的参数，对应 GDB 的 x/<count><format><size>
+type ExamineSpec struct {
+	// Count 是显示的单元个数，s 格式是字符串的个数，i 格式是指令的条数，0 表示没有指定
+	Count  int
+	Format byte
+	// Size 是每个单元的字节数，1/2/4/8 分别对应 b/h/w/g
+	Size uint64
+}
+
+// lastExamine 是上一次 x 命令使用的格式和大小，和 GDB 一样，没有指定的时候沿用上一次的
+var lastExamine = ExamineSpec{Format: 'x', Size: 1}
+
+// examineSizes 是大小字母对应的字节数
+var examineSizes = map[byte]uint64{'b': 1, 'h': 2, 'w': 4, 'g': 8}
+
+// ParseExamineSpec 解析 /10xg 这样的参数，格式和大小的字母可以是任意顺序
+// 没有指定的格式和大小沿用 last
+func ParseExamineSpec(spec string, last ExamineSpec) (ExamineSpec, error) {
+	result := ExamineSpec{Format: last.Format, Size: last.Size}
+	s := strings.TrimPrefix(spec, "/")
+	digits := 0
+	for digits < len(s) && s[digits] >= '0' && s[digits] <= '9' {
+		digits++
+	}
+	if digits > 0 {
+		count, err := strconv.Atoi(s[:digits])
+		if err != nil {
+			return result, err
+		}
+		if count <= 0 {
+			return result, fmt.Errorf("invalid count %d", count)
+		}
+		result.Count = count
+	}
+	sizeSet := false
+	for _, letter := range []byte(s[digits:]) {
+		if size, ok := examineSizes[letter]; ok {
+			result.Size = size
+			sizeSet = true
+		} else if strings.IndexByte(ExamineFormats, letter) >= 0 {
+			result.Format = letter
+		} else {
+			return result, fmt.Errorf("unknown examine format or size %q in %s", letter, spec)
+		}
+	}
+	// 和 GDB 一样，有些格式对大小有要求
+	switch result.Format {
+	case 'c':
+		if !sizeSet {
+			result.Size = 1
+		}
+	case 'a':
+		result.Size = 8
+	case 'f':
+		if result.Size != 4 && result.Size != 8 {
+			result.Size = 8
+		}
+	}
+	return result, nil
+}
+
+// Bytes 得到数值格式需要读取的字节数
+func (spec ExamineSpec) Bytes() uint64 {
+	if spec.Count == 0 {
+		return defaultExamineBytes
+	}
+	return uint64(spec.Count) * spec.Size
+}
+
+// examinePerLine 得到每行显示的单元个数
+func examinePerLine(spec ExamineSpec) int {
+	switch spec.Format {
+	case 'a':
+		return 1
+	case 't':
+		return int(8 / spec.Size)
+	case 'c':
+		return 8
+	}
+	switch spec.Size {
+	case 1, 2:
+		return 8
+	case 4:
+		return 4
+	default:
+		return 2
+	}
+}
+
+// formatExamineUnit 按照格式显示一个单元
+func formatExamineUnit(data []byte, format byte) string {
+	var value uint64
+	switch len(data) {
+	case 1:
+		value = uint64(data[0])
+	case 2:
+		value = uint64(binary.LittleEndian.Uint16(data))
+	case 4:
+		value = uint64(binary.LittleEndian.Uint32(data))
+	default:
+		value = binary.LittleEndian.Uint64(data)
+	}
+	bits := uint(len(data) * 8)
+	switch format {
+	case 'd':
+		// 符号扩展
+		return strconv.FormatInt(int64(value<<(64-bits))>>(64-bits), 10)
+	case 'u':
+		return strconv.FormatUint(value, 10)
+	case 'o':
+		return fmt.Sprintf("0%o", value)
+	case 't':
+		return fmt.Sprintf("%0*b", bits, value)
+	case 'c':
+		r := rune(value)
+		if r < 0x20 || r >= 0x7f {
+			return fmt.Sprintf("%d '\\x%02x'", int8(value), value)
+		}
+		return fmt.Sprintf("%d '%s'", int8(value), tview.Escape(string(r)))
+	case 'f':
+		if len(data) == 4 {
+			return strconv.FormatFloat(float64(math.Float32frombits(uint32(value))), 'g', -1, 32)
+		}
+		return strconv.FormatFloat(math.Float64frombits(value), 'g', -1, 64)
+	case 'a':
+		line := fmt.Sprintf("0x%016x", value)
+		if annotation := Telescope(value); annotation != "" {
+			line += "  [yellow]" + annotation + "[white]"
+		}
+		return line
+	default:
+		return fmt.Sprintf("0x%0*x", len(data)*2, value)
+	}
+}
+
+// asciiSidebar 得到十六进制输出右侧的 ASCII 显示，不可打印的字符显示为 .
+func asciiSidebar(data []byte) string {
+	sb := strings.Builder{}
+	for _, b := range data {
+		if b >= 0x20 && b < 0x7f {
+			sb.WriteByte(b)
+		} else {
+			sb.WriteByte('.')
+		}
+	}
+	return "|" + tview.Escape(sb.String()) + "|"
+}
+
+// FormatMemory 按照 spec 格式化数值格式的数据，例如 x/4xg addr 会变成每行 2 个 8 字节的数据
+// 十六进制格式会在右侧显示 ASCII
+func FormatMemory(mems []byte, start uint64, spec ExamineSpec) []string {
+	size := int(spec.Size)
+	units := make([]string, 0, len(mems)/size)
+	width := 0
+	for offset := 0; offset+size <= len(mems); offset += size {
+		unit := formatExamineUnit(mems[offset:offset+size], spec.Format)
+		units = append(units, unit)
+		if len(unit) > width {
+			width = len(unit)
+		}
+	}
+	perLine := examinePerLine(spec)
+	result := make([]string, 0, len(units)/perLine+1)
+	for i := 0; i < len(units); i += perLine {
+		end := i + perLine
+		if end > len(units) {
+			end = len(units)
+		}
+		sb := strings.Builder{}
+		sb.WriteString(fmt.Sprintf("0x%x    ", start+uint64(i*size)))
+		for j := i; j < i+perLine; j++ {
+			if j >= end {
+				if spec.Format != 'x' {
+					break
+				}
+				// 补齐最后一行，让 ASCII 对齐
+				sb.WriteString(strings.Repeat(" ", width+1))
+				continue
+			}
+			if spec.Format == 'a' {
+				sb.WriteString(units[j])
+				continue
+			}
+			sb.WriteString(fmt.Sprintf("%-*s ", width, units[j]))
+		}
+		if spec.Format == 'x' {
+			sb.WriteString(" " + asciiSidebar(mems[i*size:end*size]))
+		}
+		result = append(result, strings.TrimRight(sb.String(), " "))
+	}
+	return result
+}
+
+// FormatStrings 把 data 按照 C 字符串格式化，最多 count 个，每行一个
+func FormatStrings(data []byte, start uint64, count int) []string {
+	result := make([]string, 0, count)
+	offset := 0
+	for len(result) < count && offset < len(data) {
+		end := offset
+		for end < len(data) && data[end] != 0 {
+			end++
+		}
+		s := tview.Escape(strconv.Quote(string(data[offset:end])))
+		if end == len(data) {
+			// 没有读到字符串的结尾
+			s += "..."
+		}
+		result = append(result, fmt.Sprintf("0x%x    %s", start+uint64(offset), s))
+		offset = end + 1
+	}
+	return result
+}
+
+// countStrings 得到 data 中以 0 结尾的字符串的个数
+func countStrings(data []byte) int {
+	count := 0
+	for _, b := range data {
+		if b == 0 {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,473 @@
+package UI
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ExprEnv 是表达式求值时取得寄存器、内存、符号和变量的接口
+type ExprEnv interface {
+	// Register 取得寄存器的值，name 是小写的寄存器名，不带 $
+	Register(name string) (uint64, error)
+	// ReadMemory 读取 address 开始的 size 个字节
+	ReadMemory(address uint64, size int) ([]byte, error)
+	// Symbol 取得全局变量（符号）的地址，用于 &sym
+	Symbol(name string) (uint64, error)
+	// Variable 取得 Go 变量的值
+	Variable(name string) (uint64, error)
+}
+
+// clientEnv 通过 delve 求值
+type clientEnv struct{}
+
+func (clientEnv) Register(name string) (uint64, error) {
+	return registerValue(name)
+}
+
+func (clientEnv) ReadMemory(address uint64, size int) ([]byte, error) {
+	return client.ExamineMemory(address, size)
+}
+
+func (clientEnv) Symbol(name string) (uint64, error) {
+	return client.SymbolAddress(name)
+}
+
+func (clientEnv) Variable(name string) (uint64, error) {
+	return client.VariableValue(name)
+}
+
+// frameEnv 在某一帧中求值，寄存器和变量都是该帧的
+type frameEnv struct {
+	frame int
+}
+
+func (e frameEnv) Register(name string) (uint64, error) {
+	return frameRegisterValue(e.frame, name)
+}
+
+func (frameEnv) ReadMemory(address uint64, size int) ([]byte, error) {
+	return client.ExamineMemory(address, size)
+}
+
+func (frameEnv) Symbol(name string) (uint64, error) {
+	return client.SymbolAddress(name)
+}
+
+func (e frameEnv) Variable(name string) (uint64, error) {
+	return client.VariableValueInFrame(e.frame, name)
+}
+
+// ExprError 是表达式的错误，Pos 是出错的位置，显示时用 ^ 标出
+type ExprError struct {
+	Expr string
+	Pos  int
+	Msg  string
+}
+
+func (e *ExprError) Error() string {
+	return fmt.Sprintf("%s\n%s\n%s^", e.Msg, e.Expr, strings.Repeat(" ", e.Pos))
+}
+
+// derefTypes 是解引用时可以使用的类型，例如 *(u32*)addr，值是字节数，负数表示有符号
+var derefTypes = map[string]int{
+	"u8": 1, "u16": 2, "u32": 4, "u64": 8,
+	"i8": -1, "i16": -2, "i32": -4, "i64": -8,
+	"uint8": 1, "uint16": 2, "uint32": 4, "uint64": 8, "uintptr": 8,
+	"int8": -1, "int16": -2, "int32": -4, "int64": -8, "int": -8, "uint": 8,
+	"byte": 1, "ptr": 8,
+}
+
+// token 的类型
+const (
+	tokenEOF = iota
+	tokenNumber
+	tokenRegister
+	tokenIdent
+	tokenOperator
+)
+
+type token struct {
+	kind int
+	text string
+	pos  int
+}
+
+// exprParser 是递归下降的表达式解析器，一边解析一边求值，所有的运算都是 64 位无符号整数
+// 优先级和 C 一样，从低到高：| ^ & << >> + - * / % 单目运算符
+type exprParser struct {
+	expr   string
+	tokens []token
+	index  int
+	env    ExprEnv
+}
+
+// twoCharOperators 是两个字符的运算符
+var twoCharOperators = []string{"<<", ">>"}
+
+// isIdentChar 判断是不是变量名中的字符，包名和字段用 . 连接
+func isIdentChar(c byte, first bool) bool {
+	if c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' {
+		return true
+	}
+	return !first && (c >= '0' && c <= '9' || c == '.')
+}
+
+// tokenize 把表达式分成 token
+func tokenize(expr string) ([]token, error) {
+	tokens := make([]token, 0)
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c >= '0' && c <= '9':
+			start := i
+			for i < len(expr) && (isIdentChar(expr[i], false) && expr[i] != '.') {
+				i++
+			}
+			tokens = append(tokens, token{tokenNumber, expr[start:i], start})
+		case c == '$':
+			start := i
+			i++
+			for i < len(expr) && isIdentChar(expr[i], false) && expr[i] != '.' {
+				i++
+			}
+			// st(0) 这样的 x87 寄存器
+			if strings.EqualFold(expr[start+1:i], "st") && i+2 < len(expr) && expr[i] == '(' && expr[i+2] == ')' {
+				i += 3
+			}
+			if i == start+1 {
+				return nil, &ExprError{expr, start, "missing register name after $"}
+			}
+			tokens = append(tokens, token{tokenRegister, expr[start+1 : i], start})
+		case c == '"':
+			// delve 中带 / 的包名要加引号，例如 "net/http".DefaultClient
+			start := i
+			end := strings.IndexByte(expr[i+1:], '"')
+			if end < 0 {
+				return nil, &ExprError{expr, start, "unterminated quoted package path"}
+			}
+			i += end + 2
+			for i < len(expr) && isIdentChar(expr[i], false) {
+				i++
+			}
+			tokens = append(tokens, token{tokenIdent, expr[start:i], start})
+		case isIdentChar(c, true):
+			start := i
+			for i < len(expr) && isIdentChar(expr[i], false) {
+				i++
+			}
+			tokens = append(tokens, token{tokenIdent, expr[start:i], start})
+		case strings.IndexByte("+-*/%&|^~!()", c) >= 0 || c == '<' || c == '>':
+			text := string(c)
+			for _, op := range twoCharOperators {
+				if strings.HasPrefix(expr[i:], op) {
+					text = op
+				}
+			}
+			if text == "<" || text == ">" {
+				return nil, &ExprError{expr, i, fmt.Sprintf("unknown operator %s", text)}
+			}
+			tokens = append(tokens, token{tokenOperator, text, i})
+			i += len(text)
+		default:
+			return nil, &ExprError{expr, i, fmt.Sprintf("unexpected character %q", c)}
+		}
+	}
+	return append(tokens, token{tokenEOF, "", len(expr)}), nil
+}
+
+// EvaluateExpression 计算表达式的值
+// 支持 0x/0b/0o 和十进制的数、$rsp 这样的寄存器、Go 变量、&symbol、*(u32*)addr 这样的解引用，
+// 以及 + - * / % & | ^ << >> ~ 运算，所有的运算都是 64 位无符号整数
+func EvaluateExpression(expr string, env ExprEnv) (uint64, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return 0, err
+	}
+	p := &exprParser{expr: expr, tokens: tokens, env: env}
+	if p.peek().kind == tokenEOF {
+		return 0, p.errorAt(p.peek(), "empty expression")
+	}
+	value, err := p.parseBinary(0)
+	if err != nil {
+		return 0, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return 0, p.errorAt(t, fmt.Sprintf("unexpected %s", t.text))
+	}
+	return value, nil
+}
+
+// UsesFrame 判断表达式是否依赖当前的帧，也就是用到了寄存器或者局部变量
+// 带包名的变量（例如 main.count）和 &symbol 是全局的
+func UsesFrame(expr string) bool {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return false
+	}
+	for i, t := range tokens {
+		switch t.kind {
+		case tokenRegister:
+			return true
+		case tokenIdent:
+			if i > 0 && tokens[i-1].text == "&" {
+				continue
+			}
+			// *(u64*) 中的类型名
+			if _, ok := derefTypes[strings.ToLower(t.text)]; ok && tokens[i+1].text == "*" {
+				continue
+			}
+			if !strings.Contains(t.text, ".") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// EvaluateAddress 使用 delve 计算表达式的值，用于命令中的地址
+func EvaluateAddress(expr string) (uint64, error) {
+	return EvaluateExpression(expr, clientEnv{})
+}
+
+// parseLocation 解析命令中的位置，可以是地址表达式，也可以是 delve 能识别的符号（函数名、file:line 等）
+// 不能解析成表达式，或者只是一个标识符（例如 main.main）时当作符号，避免把全局变量的值当作地址
+func parseLocation(text string) (address uint64, isSymbol bool, err error) {
+	tokens, err := tokenize(text)
+	if err != nil || (len(tokens) == 2 && tokens[0].kind == tokenIdent) {
+		return 0, true, nil
+	}
+	address, err = EvaluateAddress(text)
+	if err != nil {
+		// 例如 net/http.Serve 也能被解析成除法，delve 能找到这个位置的话当作符号
+		if _, locErr := client.FindLocationByName(text); locErr == nil {
+			return 0, true, nil
+		}
+		return 0, false, err
+	}
+	return address, false, nil
+}
+
+func (p *exprParser) peek() token {
+	return p.tokens[p.index]
+}
+
+func (p *exprParser) next() token {
+	t := p.tokens[p.index]
+	if t.kind != tokenEOF {
+		p.index++
+	}
+	return t
+}
+
+func (p *exprParser) errorAt(t token, msg string) error {
+	return &ExprError{p.expr, t.pos, msg}
+}
+
+// expect 读取下一个运算符，不是 op 时返回错误
+func (p *exprParser) expect(op string) error {
+	t := p.next()
+	if t.kind != tokenOperator || t.text != op {
+		if t.kind == tokenEOF {
+			return p.errorAt(t, fmt.Sprintf("expected %s at end of expression", op))
+		}
+		return p.errorAt(t, fmt.Sprintf("expected %s, got %s", op, t.text))
+	}
+	return nil
+}
+
+// binaryLevels 是二元运算符的优先级，从低到高
+var binaryLevels = [][]string{
+	{"|"}, {"^"}, {"&"}, {"<<", ">>"}, {"+", "-"}, {"*", "/", "%"},
+}
+
+// parseBinary 解析优先级不低于 level 的二元运算
+func (p *exprParser) parseBinary(level int) (uint64, error) {
+	if level == len(binaryLevels) {
+		return p.parseUnary()
+	}
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return 0, err
+	}
+	for {
+		t := p.peek()
+		if t.kind != tokenOperator || !containsString(binaryLevels[level], t.text) {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return 0, err
+		}
+		switch t.text {
+		case "|":
+			left |= right
+		case "^":
+			left ^= right
+		case "&":
+			left &= right
+		case "<<":
+			left <<= right
+		case ">>":
+			left >>= right
+		case "+":
+			left += right
+		case "-":
+			left -= right
+		case "*":
+			left *= right
+		case "/", "%":
+			if right == 0 {
+				return 0, p.errorAt(t, "division by zero")
+			}
+			if t.text == "/" {
+				left /= right
+			} else {
+				left %= right
+			}
+		}
+	}
+}
+
+// containsString 判断 s 是否在 list 中
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// parseUnary 解析单目运算符、解引用和取地址
+func (p *exprParser) parseUnary() (uint64, error) {
+	t := p.peek()
+	if t.kind != tokenOperator {
+		return p.parsePrimary()
+	}
+	switch t.text {
+	case "-", "~", "!", "+":
+		p.next()
+		value, err := p.parseUnary()
+		if err != nil {
+			return 0, err
+		}
+		switch t.text {
+		case "-":
+			return -value, nil
+		case "~":
+			return ^value, nil
+		case "!":
+			if value == 0 {
+				return 1, nil
+			}
+			return 0, nil
+		}
+		return value, nil
+	case "*":
+		p.next()
+		return p.parseDeref(t)
+	case "&":
+		p.next()
+		name := p.next()
+		if name.kind != tokenIdent {
+			return 0, p.errorAt(name, "expected a symbol name after &")
+		}
+		address, err := p.env.Symbol(name.text)
+		if err != nil {
+			return 0, p.errorAt(name, fmt.Sprintf("unknown symbol %s: %v", name.text, err))
+		}
+		return address, nil
+	}
+	return p.parsePrimary()
+}
+
+// parseDeref 解析 * 之后的部分，可以是 *(u32*)addr，也可以是 *addr（读取 8 个字节）
+func (p *exprParser) parseDeref(star token) (uint64, error) {
+	size := 8
+	// 向前看是不是 (type*)
+	if p.index+3 < len(p.tokens) {
+		open, name, ptr, closing := p.tokens[p.index], p.tokens[p.index+1], p.tokens[p.index+2], p.tokens[p.index+3]
+		if open.text == "(" && name.kind == tokenIdent && ptr.text == "*" && closing.text == ")" {
+			s, ok := derefTypes[strings.ToLower(name.text)]
+			if !ok {
+				return 0, p.errorAt(name, fmt.Sprintf("unknown type %s", name.text))
+			}
+			size = s
+			p.index += 4
+		}
+	}
+	address, err := p.parseUnary()
+	if err != nil {
+		return 0, err
+	}
+	signed := size < 0
+	if signed {
+		size = -size
+	}
+	data, err := p.env.ReadMemory(address, size)
+	if err != nil {
+		return 0, p.errorAt(star, fmt.Sprintf("cannot read memory at 0x%x: %v", address, err))
+	}
+	buf := make([]byte, 8)
+	copy(buf, data[:size])
+	value := binary.LittleEndian.Uint64(buf)
+	if signed {
+		shift := uint(64 - size*8)
+		value = uint64(int64(value<<shift) >> shift)
+	}
+	return value, nil
+}
+
+// parsePrimary 解析数、寄存器、变量和括号
+func (p *exprParser) parsePrimary() (uint64, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenNumber:
+		value, err := parseNumber(t.text)
+		if err != nil {
+			return 0, p.errorAt(t, fmt.Sprintf("invalid number %s", t.text))
+		}
+		return value, nil
+	case tokenRegister:
+		value, err := p.env.Register(strings.ToLower(t.text))
+		if err != nil {
+			return 0, p.errorAt(t, fmt.Sprintf("unknown register $%s", t.text))
+		}
+		return value, nil
+	case tokenIdent:
+		value, err := p.env.Variable(t.text)
+		if err != nil {
+			return 0, p.errorAt(t, fmt.Sprintf("cannot evaluate %s: %v", t.text, err))
+		}
+		return value, nil
+	case tokenOperator:
+		if t.text == "(" {
+			value, err := p.parseBinary(0)
+			if err != nil {
+				return 0, err
+			}
+			return value, p.expect(")")
+		}
+		return 0, p.errorAt(t, fmt.Sprintf("unexpected %s", t.text))
+	}
+	return 0, p.errorAt(t, "unexpected end of expression")
+}
+
+// parseNumber 解析 64 位无符号整数，支持 0x、0b、0o 前缀
+func parseNumber(text string) (uint64, error) {
+	lower := strings.ToLower(text)
+	switch {
+	case strings.HasPrefix(lower, "0x"):
+		return strconv.ParseUint(lower[2:], 16, 64)
+	case strings.HasPrefix(lower, "0b"):
+		return strconv.ParseUint(lower[2:], 2, 64)
+	case strings.HasPrefix(lower, "0o"):
+		return strconv.ParseUint(lower[2:], 8, 64)
+	}
+	return strconv.ParseUint(lower, 10, 64)
+}
@@ -0,0 +1,243 @@
+package UI
+
+import (
+	"MyDebugger/src/api"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// findChunkSize 是查找时一次读取的字节数
+const findChunkSize = 0x10000
+
+// maxFindBytes 是一次最多查找的范围，delve 一次只能读取不到 1000 个字节，太大的范围要很多次 rpc
+const maxFindBytes = 0x1000000
+
+// maxFindHits 是最多显示的结果个数
+const maxFindHits = 256
+
+// SearchPattern 是要查找的字节序列，mask 为 false 的字节是通配符
+type SearchPattern struct {
+	Bytes []byte
+	Mask  []bool
+}
+
+// ParseSearchPattern 解析查找的内容
+// "text" 是 UTF-8 字符串，u"text" 是 UTF-16 字符串
+// b:/h:/w:/g: 加数字是对应大小的小端序整数，0x 开头的数是 8 字节的值（例如指针）
+// 其他的是十六进制字节序列，例如 48 8b ?? 05 或者 488b??05，?? 是通配符
+func ParseSearchPattern(pattern string) (SearchPattern, error) {
+	result, err := parseSearchPattern(strings.TrimSpace(pattern))
+	if err == nil && len(result.Bytes) == 0 {
+		// 空的查找内容会匹配每一个地址
+		return SearchPattern{}, fmt.Errorf("empty pattern")
+	}
+	return result, err
+}
+
+// parseSearchPattern 按照查找内容的格式解析，可能得到空的查找内容
+func parseSearchPattern(pattern string) (SearchPattern, error) {
+	switch {
+	case pattern == "":
+		return SearchPattern{}, fmt.Errorf("empty pattern")
+	case strings.HasPrefix(pattern, `"`):
+		s, err := strconv.Unquote(pattern)
+		if err != nil {
+			return SearchPattern{}, err
+		}
+		return exactPattern([]byte(s)), nil
+	case strings.HasPrefix(pattern, `u"`):
+		s, err := strconv.Unquote(pattern[1:])
+		if err != nil {
+			return SearchPattern{}, err
+		}
+		data := make([]byte, 0, len(s)*2)
+		for _, unit := range utf16.Encode([]rune(s)) {
+			data = binary.LittleEndian.AppendUint16(data, unit)
+		}
+		return exactPattern(data), nil
+	case len(pattern) > 2 && pattern[1] == ':' && examineSizes[pattern[0]] != 0:
+		return integerPattern(pattern[2:], examineSizes[pattern[0]])
+	case strings.HasPrefix(pattern, "0x") && !strings.Contains(pattern, " "):
+		return integerPattern(pattern, 8)
+	}
+	return hexPattern(pattern)
+}
+
+// exactPattern 得到没有通配符的查找内容
+func exactPattern(data []byte) SearchPattern {
+	mask := make([]bool, len(data))
+	for i := range mask {
+		mask[i] = true
+	}
+	return SearchPattern{Bytes: data, Mask: mask}
+}
+
+// integerPattern 把整数按照小端序转换成 size 个字节，可以是负数
+func integerPattern(number string, size uint64) (SearchPattern, error) {
+	var value uint64
+	if strings.HasPrefix(number, "-") {
+		v, err := strconv.ParseInt(number, 0, int(size*8))
+		if err != nil {
+			return SearchPattern{}, err
+		}
+		value = uint64(v)
+	} else {
+		v, err := strconv.ParseUint(number, 0, int(size*8))
+		if err != nil {
+			return SearchPattern{}, err
+		}
+		value = v
+	}
+	data := binary.LittleEndian.AppendUint64(nil, value)
+	return exactPattern(data[:size]), nil
+}
+
+// hexPattern 解析十六进制字节序列，?? 是通配符
+func hexPattern(pattern string) (SearchPattern, error) {
+	s := strings.Join(strings.Fields(pattern), "")
+	if len(s)%2 != 0 {
+		return SearchPattern{}, fmt.Errorf("odd length hex pattern %s", pattern)
+	}
+	result := SearchPattern{Bytes: make([]byte, 0, len(s)/2), Mask: make([]bool, 0, len(s)/2)}
+	for i := 0; i < len(s); i += 2 {
+		if s[i:i+2] == "??" {
+			result.Bytes = append(result.Bytes, 0)
+			result.Mask = append(result.Mask, false)
+			continue
+		}
+		b, err := hex.DecodeString(s[i : i+2])
+		if err != nil {
+			return SearchPattern{}, fmt.Errorf("invalid hex byte %s in pattern %s", s[i:i+2], pattern)
+		}
+		result.Bytes = append(result.Bytes, b[0])
+		result.Mask = append(result.Mask, true)
+	}
+	return result, nil
+}
+
+// match 判断 data 的开头是否和查找的内容匹配
+func (pattern SearchPattern) match(data []byte) bool {
+	for i, b := range pattern.Bytes {
+		if pattern.Mask[i] && data[i] != b {
+			return false
+		}
+	}
+	return true
+}
+
+// SearchMemory 在从 start 开始的 data 中查找，返回所有匹配的地址
+func SearchMemory(data []byte, start uint64, pattern SearchPattern) []uint64 {
+	result := make([]uint64, 0)
+	if len(pattern.Bytes) == 0 {
+		return result
+	}
+	for offset := 0; offset+len(pattern.Bytes) <= len(data); offset++ {
+		if pattern.match(data[offset:]) {
+			result = append(result, start+uint64(offset))
+		}
+	}
+	return result
+}
+
+// ParseSearchRange 解析查找的范围，end 可以是结束地址，也可以是 +len
+func ParseSearchRange(start, end string) (uint64, uint64, error) {
+	low, err := EvaluateAddress(start)
+	if err != nil {
+		return 0, 0, err
+	}
+	var high uint64
+	if strings.HasPrefix(end, "+") {
+		length, err := EvaluateAddress(end[1:])
+		if err != nil {
+			return 0, 0, err
+		}
+		high = low + length
+	} else {
+		high, err = EvaluateAddress(end)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	if high <= low {
+		return 0, 0, fmt.Errorf("invalid range 0x%x-0x%x", low, high)
+	}
+	if high-low > maxFindBytes {
+		return 0, 0, fmt.Errorf("range 0x%x is too large, at most 0x%x", high-low, maxFindBytes)
+	}
+	return low, high, nil
+}
+
+// FindInMemory 用 read 读取 [start, end) 并查找 pattern，一段一段地查找，相邻的两段重叠 len(pattern)-1 个字节，以免漏掉跨段的结果
+// 返回匹配的地址、不能读取的地址范围，以及结果是否因为太多被截断
+func FindInMemory(read MyApi.MemoryReader, start, end uint64, pattern SearchPattern) ([]uint64, []MyApi.AddressRange, bool) {
+	result := make([]uint64, 0)
+	unreadable := make([]MyApi.AddressRange, 0)
+	overlap := uint64(len(pattern.Bytes) - 1)
+	for chunk := start; chunk < end; chunk += findChunkSize {
+		chunkEnd := chunk + findChunkSize + overlap
+		if chunkEnd > end {
+			chunkEnd = end
+		}
+		segments, failed := MyApi.ReadMemoryRanges(read, chunk, int(chunkEnd-chunk))
+		for _, segment := range segments {
+			for _, hit := range SearchMemory(segment.Data, segment.Address, pattern) {
+				// 重叠部分的结果在下一段中
+				if hit < chunk+findChunkSize {
+					result = append(result, hit)
+				}
+			}
+		}
+		for _, r := range failed {
+			// 重叠部分在下一段中报告
+			if r.End > chunk+findChunkSize {
+				r.End = chunk + findChunkSize
+			}
+			if r.Start >= r.End {
+				continue
+			}
+			if last := len(unreadable) - 1; last >= 0 && unreadable[last].End == r.Start {
+				unreadable[last].End = r.End
+			} else {
+				unreadable = append(unreadable, r)
+			}
+		}
+		if len(result) >= maxFindHits {
+			return result[:maxFindHits], unreadable, true
+		}
+	}
+	return result, unreadable, false
+}
+
+// symbolize 得到地址对应的符号，例如 main.main+0x10，不在函数中时返回空字符串
+func symbolize(address uint64) string {
+	if name, offset, err := client.FunctionOfAddress(address); err == nil {
+		return fmt.Sprintf("%s+0x%x", name, offset)
+	}
+	return ""
+}
+
+// FindMemory 在 [start, end) 中查找 pattern，显示所有匹配的地址
+func (info *viewInfo) FindMemory(start, end uint64, pattern SearchPattern) error {
+	hits, unreadable, truncated := FindInMemory(client.ReadMemory, start, end, pattern)
+	result := make([]string, 0, len(hits)+len(unreadable)+2)
+	result = append(result, fmt.Sprintf("[yellow]; 在 0x%x-0x%x 中找到 %d 个结果[white]", start, end, len(hits)))
+	for _, r := range unreadable {
+		result = append(result, fmt.Sprintf("[red]; 0x%x-0x%x 不能读取，没有查找[white]", r.Start, r.End))
+	}
+	for _, hit := range hits {
+		line := fmt.Sprintf("0x%x", hit)
+		if symbol := symbolize(hit); symbol != "" {
+			line += "    <" + symbol + ">"
+		}
+		result = append(result, line)
+	}
+	if truncated {
+		result = append(result, fmt.Sprintf("[yellow]; 只显示前 %d 个结果[white]", maxFindHits))
+	}
+	info.data = result
+	return nil
+}
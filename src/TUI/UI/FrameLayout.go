@@ -0,0 +1,110 @@
+package UI
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/go-delve/delve/service/api"
+	"strings"
+)
+
+// maxFrameLayoutSize 是栈帧视图最多显示的字节数
+const maxFrameLayoutSize = 0x800
+
+// FormatFrameLayout 格式化栈帧，每行一个 8 字节的槽位
+// labels 是槽位的说明，boundaries 是在某个地址之前显示的栈帧分界线
+func FormatFrameLayout(mems []byte, start uint64, labels map[uint64][]string, boundaries map[uint64]string) []string {
+	result := make([]string, 0, len(mems)/8+2)
+	for offset := 0; offset+8 <= len(mems); offset += 8 {
+		address := start + uint64(offset)
+		if boundary, ok := boundaries[address]; ok {
+			result = append(result, fmt.Sprintf("[yellow]; ---- %s ----[white]", boundary))
+		}
+		value := binary.LittleEndian.Uint64(mems[offset:])
+		line := fmt.Sprintf("0x%x    0x%016x", address, value)
+		if label, ok := labels[address]; ok {
+			line += "  [yellow]" + strings.Join(label, ", ") + "[white]"
+		}
+		result = append(result, line)
+	}
+	return result
+}
+
+// addSlotLabel 给变量所在的槽位加上说明，没有按 8 字节对齐的变量会注明偏移
+func addSlotLabel(labels map[uint64][]string, address uint64, label string) {
+	slot := address &^ 7
+	if address != slot {
+		label = fmt.Sprintf("%s (+%d)", label, address-slot)
+	}
+	labels[slot] = append(labels[slot], label)
+}
+
+// frameLayout 得到第 n 帧的栈帧布局
+// 返回从低地址开始的内存、起始地址、槽位说明和栈帧分界线
+func frameLayout(n int) ([]byte, uint64, map[uint64][]string, map[uint64]string, error) {
+	frames, err := client.StacktraceWithVariables(n + 1)
+	if err != nil {
+		return nil, 0, nil, nil, err
+	}
+	if n >= len(frames) {
+		return nil, 0, nil, nil, fmt.Errorf("frame %d does not exist", n)
+	}
+	// FrameOffset 和 FramePointerOffset 都是相对栈顶（stack.hi）的偏移
+	// 第 0 帧的 rbp 就是当前的 Rbp 寄存器，由此得到栈顶，再得到每一帧的 CFA
+	// 调用栈和寄存器必须来自同一个线程，没有协程时 client 按 GoroutineID 0 取当前线程的调用栈
+	stackHi := int64(client.Current.Rbp) - frames[0].FramePointerOffset
+	cfa := func(i int) uint64 {
+		return uint64(stackHi + frames[i].FrameOffset)
+	}
+	frame := frames[n]
+	high := cfa(n)
+	low := client.Current.Rsp
+	if n > 0 {
+		low = cfa(n - 1)
+	}
+	if high-low > maxFrameLayoutSize {
+		low = high - maxFrameLayoutSize
+	}
+
+	labels := make(map[uint64][]string)
+	boundaries := map[uint64]string{
+		low:  fmt.Sprintf("frame %d: %s", n, frame.Function.Name()),
+		high: "caller frame",
+	}
+	if n+1 < len(frames) {
+		boundaries[high] = fmt.Sprintf("caller frame %d: %s", n+1, frames[n+1].Function.Name())
+	}
+	// Go 的函数序言是 push rbp; mov rbp, rsp，所以 rbp 指向保存的 rbp
+	if uint64(stackHi+frame.FramePointerOffset) == high-16 {
+		labels[high-16] = []string{"saved rbp"}
+	}
+
+	end := high + 8
+	addVariables := func(variables []api.Variable, kind string) {
+		for _, variable := range variables {
+			if variable.Addr < low || variable.Addr >= high+maxFrameLayoutSize {
+				// 在寄存器中或者逃逸到堆上的变量
+				continue
+			}
+			label := fmt.Sprintf("%s %s %s", kind, variable.Name, variable.Type)
+			if variable.Addr >= high {
+				// ABIInternal 中寄存器参数的溢出槽位在调用者的栈帧中
+				label += " (spill slot)"
+				if variable.Addr+8 > end {
+					end = (variable.Addr + 8 + 7) &^ 7
+				}
+			}
+			addSlotLabel(labels, variable.Addr, label)
+		}
+	}
+	addVariables(frame.Arguments, "arg")
+	addVariables(frame.Locals, "local")
+
+	// 栈帧可能比 delve 一次能读取的多，ReadMemory 会分段读取
+	mems, err := client.ReadMemory(low, int(end-low))
+	if err != nil {
+		return nil, 0, nil, nil, err
+	}
+	returnAddress := binary.LittleEndian.Uint64(mems[high-8-low:])
+	labels[high-8] = append([]string{strings.TrimSpace("return address " + Telescope(returnAddress))}, labels[high-8]...)
+	return mems, low, labels, boundaries, nil
+}
@@ -0,0 +1,268 @@
+package UI
+
+import (
+	"debug/dwarf"
+	"debug/elf"
+	"fmt"
+	"github.com/rivo/tview"
+	"sort"
+)
+
+// TypeIndex 是可执行文件中的类型名到 DWARF 中位置的索引
+type TypeIndex struct {
+	path    string
+	data    *dwarf.Data
+	offsets map[string]dwarf.Offset
+}
+
+// typeIndex 缓存上一次读取的可执行文件的类型索引
+var typeIndex *TypeIndex
+
+// typeTags 是表示类型的 DWARF 条目
+var typeTags = map[dwarf.Tag]bool{
+	dwarf.TagBaseType:       true,
+	dwarf.TagStructType:     true,
+	dwarf.TagTypedef:        true,
+	dwarf.TagPointerType:    true,
+	dwarf.TagArrayType:      true,
+	dwarf.TagSubroutineType: true,
+}
+
+// LoadTypeIndex 读取可执行文件的 DWARF，建立类型名的索引
+func LoadTypeIndex(path string) (*TypeIndex, error) {
+	file, err := elf.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	data, err := file.DWARF()
+	if err != nil {
+		return nil, err
+	}
+	index := &TypeIndex{
+		path:    path,
+		data:    data,
+		offsets: make(map[string]dwarf.Offset),
+	}
+	reader := data.Reader()
+	for {
+		entry, err := reader.Next()
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			break
+		}
+		if !typeTags[entry.Tag] {
+			continue
+		}
+		name, ok := entry.Val(dwarf.AttrName).(string)
+		if !ok {
+			continue
+		}
+		// Go 的具名结构体同时有 StructType 和 Typedef，用第一个就可以
+		if _, ok := index.offsets[name]; !ok {
+			index.offsets[name] = entry.Offset
+		}
+	}
+	return index, nil
+}
+
+// Lookup 根据类型名找到类型，例如 main.T、net/http.Request、[]int
+func (index *TypeIndex) Lookup(name string) (dwarf.Type, error) {
+	offset, ok := index.offsets[name]
+	if !ok {
+		return nil, fmt.Errorf("type %s not found", name)
+	}
+	return index.data.Type(offset)
+}
+
+// targetTypes 得到被调试的程序的类型索引，程序没有变化时使用缓存
+func targetTypes() (*TypeIndex, error) {
+	path := client.ExecutablePath()
+	if typeIndex != nil && typeIndex.path == path {
+		return typeIndex, nil
+	}
+	index, err := LoadTypeIndex(path)
+	if err != nil {
+		return nil, err
+	}
+	typeIndex = index
+	return index, nil
+}
+
+// underlyingType 去掉 typedef，得到实际的类型
+func underlyingType(t dwarf.Type) dwarf.Type {
+	for {
+		switch typedef := t.(type) {
+		case *dwarf.TypedefType:
+			t = typedef.Type
+		case *dwarf.QualType:
+			t = typedef.Type
+		default:
+			return t
+		}
+	}
+}
+
+// typeName 得到类型的名字，结构体不带 struct 前缀
+func typeName(t dwarf.Type) string {
+	if structType, ok := t.(*dwarf.StructType); ok && structType.StructName != "" {
+		return structType.StructName
+	}
+	return t.String()
+}
+
+// FieldLayout 是结构体中一个字段的布局
+type FieldLayout struct {
+	Name   string
+	Type   string
+	Offset int64
+	Size   int64
+	Align  int64
+	// Padding 是这个字段之后的填充字节数
+	Padding int64
+	typ     dwarf.Type
+}
+
+// TypeLayout 是一个类型的布局，不是结构体时 Fields 为空
+type TypeLayout struct {
+	Name   string
+	Size   int64
+	Align  int64
+	Fields []FieldLayout
+}
+
+// typeAlign 按照 Go 在 amd64 上的规则计算类型的对齐
+// 基本类型按照大小对齐（最多 8），复数按照一半的大小对齐，数组和元素一样，结构体取字段中最大的
+func typeAlign(t dwarf.Type) int64 {
+	switch t := underlyingType(t).(type) {
+	case *dwarf.StructType:
+		align := int64(1)
+		for _, field := range t.Field {
+			if a := typeAlign(field.Type); a > align {
+				align = a
+			}
+		}
+		return align
+	case *dwarf.ArrayType:
+		return typeAlign(t.Type)
+	case *dwarf.ComplexType:
+		return t.Size() / 2
+	default:
+		size := t.Size()
+		if size <= 0 {
+			return 1
+		}
+		if size > 8 {
+			return 8
+		}
+		return size
+	}
+}
+
+// alignUp 把 offset 向上对齐到 align
+func alignUp(offset, align int64) int64 {
+	return (offset + align - 1) / align * align
+}
+
+// LayoutOf 计算类型的布局，包括每个字段的偏移、大小和之后的填充
+func LayoutOf(t dwarf.Type) TypeLayout {
+	layout := TypeLayout{Name: typeName(t), Size: t.Size(), Align: typeAlign(t)}
+	structType, ok := underlyingType(t).(*dwarf.StructType)
+	if !ok {
+		return layout
+	}
+	for i, field := range structType.Field {
+		f := FieldLayout{
+			Name:   field.Name,
+			Type:   typeName(field.Type),
+			Offset: field.ByteOffset,
+			Size:   field.Type.Size(),
+			Align:  typeAlign(field.Type),
+			typ:    field.Type,
+		}
+		next := layout.Size
+		if i+1 < len(structType.Field) {
+			next = structType.Field[i+1].ByteOffset
+		}
+		f.Padding = next - f.Offset - f.Size
+		layout.Fields = append(layout.Fields, f)
+	}
+	return layout
+}
+
+// Holes 得到所有填充的字节数
+func (layout TypeLayout) Holes() int64 {
+	var holes int64
+	for _, field := range layout.Fields {
+		holes += field.Padding
+	}
+	return holes
+}
+
+// SuggestLayout 按照对齐从大到小重新排列字段，得到填充最少的布局
+// 大小为 0 的字段放在最前面，因为 Go 会给结尾的大小为 0 的字段额外分配空间
+func SuggestLayout(layout TypeLayout) TypeLayout {
+	fields := make([]FieldLayout, len(layout.Fields))
+	copy(fields, layout.Fields)
+	sort.SliceStable(fields, func(i, j int) bool {
+		if (fields[i].Size == 0) != (fields[j].Size == 0) {
+			return fields[i].Size == 0
+		}
+		return fields[i].Align > fields[j].Align
+	})
+	var offset int64
+	for i := range fields {
+		fields[i].Offset = alignUp(offset, fields[i].Align)
+		offset = fields[i].Offset + fields[i].Size
+	}
+	size := alignUp(offset, layout.Align)
+	for i := range fields {
+		next := size
+		if i+1 < len(fields) {
+			next = alignUp(fields[i].Offset+fields[i].Size, fields[i+1].Align)
+		}
+		fields[i].Padding = next - fields[i].Offset - fields[i].Size
+	}
+	return TypeLayout{Name: layout.Name, Size: size, Align: layout.Align, Fields: fields}
+}
+
+// FormatLayout 显示类型的布局：每个字段的偏移、大小、对齐和之后的填充，以及建议的字段顺序
+func FormatLayout(layout TypeLayout) []string {
+	result := []string{fmt.Sprintf("%s  size %d  align %d", tview.Escape(layout.Name), layout.Size, layout.Align)}
+	if len(layout.Fields) == 0 {
+		return result
+	}
+	result = append(result, fmt.Sprintf("%-7s %-5s %-5s %s", "offset", "size", "align", "field"))
+	for _, field := range layout.Fields {
+		result = append(result, fmt.Sprintf("+0x%-4x %-5d %-5d %s %s", field.Offset, field.Size, field.Align, field.Name, tview.Escape(field.Type)))
+		if field.Padding > 0 {
+			result = append(result, fmt.Sprintf("[yellow]+0x%-4x %-5d       (padding)[white]", field.Offset+field.Size, field.Padding))
+		}
+	}
+	result = append(result, fmt.Sprintf("[yellow]; 共有 %d 个字节的填充[white]", layout.Holes()))
+	suggested := SuggestLayout(layout)
+	if suggested.Size >= layout.Size {
+		return append(result, "[yellow]; 已经是最紧凑的字段顺序[white]")
+	}
+	result = append(result, fmt.Sprintf("[yellow]; 建议的字段顺序，size %d，可以节省 %d 个字节[white]", suggested.Size, layout.Size-suggested.Size))
+	for _, field := range suggested.Fields {
+		result = append(result, fmt.Sprintf("+0x%-4x %-5d %-5d %s %s", field.Offset, field.Size, field.Align, field.Name, tview.Escape(field.Type)))
+	}
+	return result
+}
+
+// Layout 显示类型的布局
+func (info *viewInfo) Layout(typeName string) error {
+	types, err := targetTypes()
+	if err != nil {
+		return err
+	}
+	t, err := types.Lookup(typeName)
+	if err != nil {
+		return err
+	}
+	info.data = FormatLayout(LayoutOf(t))
+	return nil
+}
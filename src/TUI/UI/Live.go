@@ -0,0 +1,172 @@
+package UI
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// liveRun 是 continue & 在后台运行的程序
+// delve 不能在程序运行的时候读内存，所以每隔 interval 让程序停下一次，读取监视器之后继续运行
+type liveRun struct {
+	interval time.Duration
+	// halted 为 1 表示用户执行了 halt，程序停下之后不再继续运行
+	halted int32
+}
+
+// monitorInterval 是后台运行时刷新监视器的间隔，为 0 时不刷新，只在程序停下时更新
+var monitorInterval = 500 * time.Millisecond
+
+// running 是正在后台运行的程序，没有时为 nil，只在 UI 的协程中访问
+var running *liveRun
+
+// errRunning 是程序在后台运行时执行其他命令的错误
+var errRunning = errors.New("the target is running, use halt to stop it")
+
+// liveCommands 是程序在后台运行时可以执行的命令，它们不会访问 delve
+var liveCommands = map[string]bool{
+	"halt": true,
+	"h":    true,
+	"help": true,
+}
+
+// title 是后台运行时监视器窗口的标题，窗口换成别的内容之后不再刷新
+func (run *liveRun) title() string {
+	if run.interval <= 0 {
+		return "监视器 (运行中)"
+	}
+	return fmt.Sprintf("监视器 (运行中，每 %v 刷新)", run.interval)
+}
+
+// continueLive 在后台运行程序，并且定时刷新监视器
+func (ui *UI) continueLive() error {
+	if running != nil {
+		return errRunning
+	}
+	if monitors.hasBreakpoints() {
+		monitors.syncWatchpoints()
+	}
+	run := &liveRun{interval: monitorInterval}
+	running = run
+	ui.MonitorView()
+	if view, ok := ui.views["fourth"]; ok {
+		view.data = monitors.getMonitorsData()
+		view.title = run.title()
+	}
+	go ui.runLive(run)
+	return nil
+}
+
+// halt 让后台运行的程序停下
+func (ui *UI) halt(args []string) error {
+	if running == nil {
+		return fmt.Errorf("the target is not running")
+	}
+	atomic.StoreInt32(&running.halted, 1)
+	return client.Halt()
+}
+
+// haltRetryInterval 是用户 halt 之后重新发送 Halt 的间隔
+// halt 可能在两次运行之间到达，被已经停下的程序忽略，所以在程序停下之前一直重试
+const haltRetryInterval = 100 * time.Millisecond
+
+// isHalted 判断用户是否执行了 halt
+func (run *liveRun) isHalted() bool {
+	return atomic.LoadInt32(&run.halted) == 1
+}
+
+// continueOnce 运行到程序停下，期间每隔 interval 调用一次 Halt
+func (run *liveRun) continueOnce() error {
+	done := client.ContinueAsync()
+	var tick <-chan time.Time
+	if run.interval > 0 {
+		ticker := time.NewTicker(run.interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	retry := time.NewTicker(haltRetryInterval)
+	defer retry.Stop()
+	halting := false
+	if run.isHalted() {
+		// 在开始运行之前用户已经 halt 了
+		halting = true
+		_ = client.Halt()
+	}
+	for {
+		select {
+		case err := <-done:
+			return err
+		case <-tick:
+			if !halting {
+				halting = true
+				// 程序可能已经因为断点停下了，这时的错误可以忽略
+				_ = client.Halt()
+			}
+		case <-retry.C:
+			if run.isHalted() {
+				halting = true
+				_ = client.Halt()
+			}
+		}
+	}
+}
+
+// runLive 在后台协程中反复运行程序，直到遇到断点、满足监视器的条件、用户 halt 或者程序退出
+// 只有 runLive 在运行期间访问 monitors，界面的更新通过 QueueUpdateDraw 交给 UI 的协程
+func (ui *UI) runLive(run *liveRun) {
+	for {
+		// 继续运行之前再检查一次，halt 可能在上一次停下之后才到达
+		if run.isHalted() {
+			ui.finishLive(nil)
+			return
+		}
+		err := run.continueOnce()
+		if err != nil {
+			ui.finishLive(err)
+			return
+		}
+		if run.isHalted() {
+			ui.finishLive(nil)
+			return
+		}
+		ids, resume := monitors.checkStop()
+		if len(ids) > 0 {
+			ui.finishLive(nil)
+			return
+		}
+		// 没有停在断点上说明是定时的 Halt，刷新监视器之后继续运行
+		if !resume && client.Current.Breakpoint != nil {
+			ui.finishLive(nil)
+			return
+		}
+		if monitors.hasBreakpoints() {
+			monitors.syncWatchpoints()
+		}
+		data := strings.Join(monitors.getMonitorsData(), "\n")
+		ui.app.QueueUpdateDraw(func() {
+			if view, ok := ui.views["fourth"]; ok && view.title == run.title() {
+				view.updateView(view.title, data)
+			}
+		})
+	}
+}
+
+// finishLive 后台运行结束之后在 UI 的协程中刷新所有的窗口，右下角显示监视器最后的值
+func (ui *UI) finishLive(err error) {
+	ui.app.QueueUpdateDraw(func() {
+		running = nil
+		ui.MonitorView()
+		if flashErr := ui.flashData(); flashErr != nil && err == nil {
+			ui.errChannel <- flashErr
+		}
+		if flashErr := ui.flashUI(); flashErr != nil {
+			ui.errChannel <- flashErr
+		}
+		ui.MonitorDataChanged()
+		if err != nil {
+			ui.errChannel <- err
+		}
+	})
+}
@@ -0,0 +1,272 @@
+package UI
+
+import (
+	"fmt"
+	"github.com/rivo/tview"
+	"sort"
+	"strings"
+)
+
+// MonitorCondition 是 monitor --when 的条件，例如 > 100、== 0x10、!= true
+type MonitorCondition struct {
+	Op    string
+	Value string
+}
+
+// conditionOps 是支持的比较运算符，两个字符的放在前面
+var conditionOps = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+// ParseMonitorCondition 解析条件，运算符后面是要比较的值，整个条件可以用单引号括起来
+// str 类型显示的值带有双引号，比较时也要写上，例如 '== "abc"'
+func ParseMonitorCondition(s string) (*MonitorCondition, error) {
+	s = strings.TrimSpace(strings.Trim(strings.TrimSpace(s), "'"))
+	for _, op := range conditionOps {
+		if !strings.HasPrefix(s, op) {
+			continue
+		}
+		value := strings.TrimSpace(s[len(op):])
+		if value == "" {
+			return nil, fmt.Errorf("missing value in condition %q", s)
+		}
+		return &MonitorCondition{Op: op, Value: value}, nil
+	}
+	return nil, fmt.Errorf("invalid condition %q, expected one of %s followed by a value", s, strings.Join(conditionOps, " "))
+}
+
+func (c *MonitorCondition) String() string {
+	return c.Op + " " + c.Value
+}
+
+// Match 判断监视器显示的值是否满足条件
+// 两边都是数的时候按照数比较，否则只支持 == 和 != 的字符串比较
+func (c *MonitorCondition) Match(value string) bool {
+	left, leftOk := numericValue(value)
+	right, rightOk := numericValue(c.Value)
+	if leftOk && rightOk {
+		switch c.Op {
+		case "==":
+			return left == right
+		case "!=":
+			return left != right
+		case "<":
+			return left < right
+		case "<=":
+			return left <= right
+		case ">":
+			return left > right
+		case ">=":
+			return left >= right
+		}
+		return false
+	}
+	switch c.Op {
+	case "==":
+		return value == c.Value
+	case "!=":
+		return value != c.Value
+	}
+	return false
+}
+
+// ParseBreakFlags 从参数中取出 --break 和 --when <condition>，返回剩下的参数
+// --when 的条件一直到下一个 -- 开头的参数为止，并且隐含了 --break
+func ParseBreakFlags(args []string) (isBreakpoint bool, condition *MonitorCondition, rest []string, err error) {
+	rest = make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--break":
+			isBreakpoint = true
+		case "--when":
+			end := i + 1
+			for end < len(args) && !strings.HasPrefix(args[end], "--") {
+				end++
+			}
+			if end == i+1 {
+				return false, nil, nil, fmt.Errorf("--when requires a condition")
+			}
+			condition, err = ParseMonitorCondition(strings.Join(args[i+1:end], " "))
+			if err != nil {
+				return false, nil, nil, err
+			}
+			isBreakpoint = true
+			i = end - 1
+		default:
+			rest = append(rest, args[i])
+		}
+	}
+	return isBreakpoint, condition, rest, nil
+}
+
+// breakInfo 是监视器停下的条件，在监视器列表中显示
+func (monitor *Monitor) breakInfo() string {
+	if !monitor.isBreakpoint {
+		return ""
+	}
+	info := "break on change"
+	if monitor.condition != nil {
+		info = "break when " + tview.Escape(monitor.condition.String())
+	}
+	if monitor.watchpoint != 0 {
+		return fmt.Sprintf("  (%s, watchpoint %d)", info, monitor.watchpoint)
+	}
+	return fmt.Sprintf("  (%s, checked at stops)", info)
+}
+
+// triggered 判断监视器是否需要让程序停下：地址没变而值变了，并且满足条件
+// address 和 data 是上一次读到的地址和值
+func (monitor *Monitor) triggered(address uint64, data string) bool {
+	if !monitor.isBreakpoint || monitor.status != "" || data == "" {
+		return false
+	}
+	if monitor.address != address || monitor.data == data {
+		return false
+	}
+	return monitor.condition == nil || monitor.condition.Match(monitor.data)
+}
+
+// canWatch 判断能否使用硬件观察点，只有 1、2、4、8 个字节的非 Go 类型可以
+func (monitor *Monitor) canWatch() bool {
+	if monitor.display.isGoType {
+		return false
+	}
+	switch monitor.display.Size {
+	case 1, 2, 4, 8:
+		return true
+	}
+	return false
+}
+
+// clearWatchpoint 删除监视器的硬件观察点
+func (monitor *Monitor) clearWatchpoint() {
+	if monitor.watchpoint != 0 {
+		// 观察点可能已经被 delve 删除（例如重新运行之后），忽略错误
+		_ = client.ClearBreakpointByID(monitor.watchpoint)
+	}
+	monitor.watchpoint = 0
+	monitor.watchAddress = 0
+}
+
+// hasBreakpoints 判断是否有需要让程序停下的监视器
+func (m *Monitors) hasBreakpoints() bool {
+	for _, monitor := range *m {
+		if monitor.isBreakpoint && !monitor.disabled {
+			return true
+		}
+	}
+	return false
+}
+
+// syncWatchpoints 让硬件观察点和监视器当前的地址保持一致
+// 地址变了的重新设置，超出作用域的删除，设置失败的在每次停下时检查
+func (m *Monitors) syncWatchpoints() {
+	existing := make(map[int]bool)
+	if points, err := client.ListBreakpoints(); err == nil {
+		for _, point := range points {
+			existing[point.ID] = true
+		}
+	}
+	for _, monitor := range *m {
+		if monitor.watchpoint != 0 && !existing[monitor.watchpoint] {
+			// 被 clear 命令或者重新运行删除了
+			monitor.watchpoint = 0
+		}
+		if !monitor.isBreakpoint || monitor.disabled || !monitor.canWatch() || monitor.status != "" || monitor.data == "" {
+			monitor.clearWatchpoint()
+			continue
+		}
+		if monitor.watchpoint != 0 && monitor.watchAddress == monitor.address {
+			continue
+		}
+		monitor.clearWatchpoint()
+		id, err := client.CreateWatchpoint(fmt.Sprintf("*(*uint%d)(0x%x)", monitor.display.Size*8, monitor.address))
+		if err != nil {
+			continue
+		}
+		monitor.watchpoint, monitor.watchAddress = id, monitor.address
+	}
+}
+
+// isWatchpoint 判断 id 是不是监视器设置的硬件观察点
+func (m *Monitors) isWatchpoint(id int) bool {
+	for _, monitor := range *m {
+		if monitor.watchpoint != 0 && monitor.watchpoint == id {
+			return true
+		}
+	}
+	return false
+}
+
+// monitorsChanged 表示 checkBreakpoints 读取监视器时发现了变化，MonitorDataChanged 据此显示变化
+// 读取之后再读一次就看不到变化了，所以要把这次的结果留下来
+var monitorsChanged bool
+
+// checkBreakpoints 重新读取监视器的值，返回需要让程序停下的监视器的编号
+func (m *Monitors) checkBreakpoints() []int {
+	addresses := make(map[*Monitor]uint64, len(*m))
+	data := make(map[*Monitor]string, len(*m))
+	for _, monitor := range *m {
+		addresses[monitor], data[monitor] = monitor.address, monitor.data
+	}
+	if m.monitorAddress() {
+		monitorsChanged = true
+	}
+	ids := make([]int, 0)
+	for _, monitor := range *m {
+		if monitor.triggered(addresses[monitor], data[monitor]) {
+			ids = append(ids, monitor.id)
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// takeChanged 返回 checkBreakpoints 之后是否有还没有显示的变化，并清除标记
+func (m *Monitors) takeChanged() bool {
+	changed := monitorsChanged
+	monitorsChanged = false
+	return changed
+}
+
+// checkStop 在程序停下之后检查监视器，返回满足条件、需要停下的监视器的编号
+// 没有满足条件的监视器，并且是因为监视器的观察点停下时，resume 为 true，表示应该继续运行
+func (m *Monitors) checkStop() (ids []int, resume bool) {
+	if ids = m.checkBreakpoints(); len(ids) > 0 {
+		return ids, false
+	}
+	point := client.Current.Breakpoint
+	return nil, point != nil && m.isWatchpoint(point.ID)
+}
+
+// runUntilMonitors 执行 continue 或者单步这样的命令，有 --break 的监视器时
+// 在监视器的值变化并满足条件时停下：能用硬件观察点的由观察点停下，其他的在每次停下时检查
+// 观察点停下但是不满足条件时继续运行：continuing 表示 run 本身就是 continue，
+// 否则只有被打断的 next/step/step-out 才继续运行（continue 会完成它们），si/ni 直接停下，以免跑远
+// 返回是否因为监视器满足条件而停下
+func runUntilMonitors(run func() error, continuing bool) (bool, error) {
+	if !monitors.hasBreakpoints() {
+		return false, run()
+	}
+	monitors.syncWatchpoints()
+	err := run()
+	for err == nil {
+		ids, resume := monitors.checkStop()
+		if len(ids) > 0 {
+			return true, nil
+		}
+		if !resume || !(continuing || client.Current.NextInProgress) {
+			return false, nil
+		}
+		monitors.syncWatchpoints()
+		err = client.Continue()
+	}
+	return false, err
+}
+
+// runWithMonitors 执行 continue，因为监视器满足条件停下时在右下角显示监视器
+func (ui *UI) runWithMonitors(run func() error) error {
+	triggered, err := runUntilMonitors(run, true)
+	if triggered {
+		ui.MonitorView()
+	}
+	return err
+}
@@ -2,29 +2,100 @@ package UI
 
 import (
 	"fmt"
+	"github.com/rivo/tview"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 )
 
+// maxMonitorHistory 是每个监视器最多保存的历史记录条数
+const maxMonitorHistory = 256
+
+// MonitorRecord 是监视器的值的一次变化
+type MonitorRecord struct {
+	// Stop 是第几次停下
+	Stop     int
+	Location string
+	// Goroutine 为 0 表示停在没有运行协程的线程上
+	Goroutine int64
+	Value     string
+}
+
 type Monitor struct {
-	size         int
-	data         string
+	// id 是监视器的编号，添加时分配，删除之后也不会再使用，监视器按照编号排列
+	id int
+	// name 是 monitor rename 起的名字，为空时显示表达式
+	name    string
+	binding Binding
+	// disabled 表示停用，不读取数据，也不设置观察点
+	disabled bool
+	// address 是最近一次计算出的地址
+	address uint64
+	display DisplayType
+	data    string
+	// old 是发生变化之前的数据
+	old string
+	// status 不为空时表示没有读到数据的原因，例如 out of scope
+	status string
+	// isBreakpoint 表示 continue 和单步时值发生变化（并且满足 condition）就停下
 	isBreakpoint bool
+	condition    *MonitorCondition
+	// watchpoint 是 delve 中硬件观察点的 ID，watchAddress 是观察的地址，为 0 时在每次停下时检查
+	watchpoint   int
+	watchAddress uint64
 	isChanged    bool
+	// history 是每次停下时值发生变化的记录，第一条是添加之后第一次读到的值
+	history []MonitorRecord
 }
 
 type Monitors map[string]*Monitor
 
 var monitors = NewMonitors()
 
+// nextMonitorID 是下一个监视器的编号
+var nextMonitorID = 1
+
 func NewMonitors() Monitors {
 	return make(Monitors)
 }
 
+// sorted 按照编号得到所有的监视器，编号在添加时分配并且不会重复使用，所以顺序是固定的
+func (m *Monitors) sorted() []*Monitor {
+	result := make([]*Monitor, 0, len(*m))
+	for _, monitor := range *m {
+		result = append(result, monitor)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].id < result[j].id
+	})
+	return result
+}
+
+// label 是监视器显示的名字，没有重命名时是表达式和作用域
+func (monitor *Monitor) label() string {
+	if monitor.name != "" {
+		return monitor.name
+	}
+	return monitor.binding.String()
+}
+
 func (m *Monitors) getMonitorsData() []string {
 	result := make([]string, 0)
-	for address, monitor := range monitors {
-		line := fmt.Sprintf("%s  %s", address, monitor.data)
+	for _, monitor := range m.sorted() {
+		label := tview.Escape(monitor.label())
+		if monitor.disabled {
+			result = append(result, fmt.Sprintf("[gray]#%d %s  disabled[white]", monitor.id, label))
+			continue
+		}
+		if monitor.status != "" {
+			result = append(result, fmt.Sprintf("[gray]#%d %s  %s[white]", monitor.id, label, monitor.status))
+			continue
+		}
+		line := fmt.Sprintf("#%d %s  0x%x  %s  %s%s", monitor.id, label, monitor.address, monitor.display, monitor.data, monitor.breakInfo())
 		if monitor.isChanged {
-			line = fmt.Sprintf("[red]%s[white]", line)
+			line = fmt.Sprintf("[red]#%d %s  0x%x  %s  %s -> %s%s[white]", monitor.id, label, monitor.address, monitor.display, monitor.old, monitor.data, monitor.breakInfo())
 			monitor.isChanged = false
 		}
 		result = append(result, line)
@@ -32,27 +103,246 @@ func (m *Monitors) getMonitorsData() []string {
 	return result
 }
 
+// getMonitorsList 列出每个监视器的编号、名字、表达式、显示类型和状态
+func (m *Monitors) getMonitorsList() []string {
+	if len(*m) == 0 {
+		return []string{"还没有监视器"}
+	}
+	result := []string{fmt.Sprintf("%-5s %-16s %-32s %-10s %s", "id", "name", "expression", "type", "state")}
+	for _, monitor := range m.sorted() {
+		state := "enabled"
+		if monitor.disabled {
+			state = "disabled"
+		}
+		result = append(result, fmt.Sprintf("%-5s %-16s %-32s %-10s %s%s",
+			fmt.Sprintf("#%d", monitor.id), tview.Escape(monitor.name), tview.Escape(monitor.binding.String()), monitor.display, state, monitor.breakInfo()))
+	}
+	return result
+}
+
+// monitorAddress 重新计算每个监视器的地址并读取数据，地址没变而数据变了的时候返回 true
 func (m *Monitors) monitorAddress() bool {
 	flag := false
-	for address, monitor := range monitors {
-		data, err := client.GetDataFromStringAddress(address, monitor.size)
-		if err != nil {
-			return false
+	for _, monitor := range *m {
+		if monitor.disabled {
+			continue
 		}
-		if monitor.data != "" && monitor.data != data {
+		address, data, status := resolveValue(monitor.binding, monitor.display)
+		if status == "" && monitor.data != "" && monitor.address == address && monitor.data != data {
 			monitor.isChanged = true
+			monitor.old = monitor.data
 			flag = true
 		}
-		monitor.data = data
+		if status == "" && (len(monitor.history) == 0 || monitor.history[len(monitor.history)-1].Value != data) {
+			monitor.record(data)
+		}
+		monitor.address, monitor.data, monitor.status = address, data, status
 	}
 	return flag
 }
 
-func (m *Monitors) add(name string, size int) {
-	(*m)[name] = &Monitor{
-		size:         size,
+// reset 清空保存的数据，重新开始调试程序之后调用，避免把新旧程序的数据当作变化
+func (m *Monitors) reset() {
+	for _, monitor := range *m {
+		monitor.data = ""
+		monitor.status = ""
+		monitor.isChanged = false
+		// 重新运行之后 delve 会删除观察点
+		monitor.watchpoint = 0
+		monitor.watchAddress = 0
+	}
+}
+
+// record 记录当前停下的位置和新的值
+func (monitor *Monitor) record(value string) {
+	location := client.Current.Function
+	if client.Current.FilePath != "" {
+		location = fmt.Sprintf("%s %s:%d", location, filepath.Base(client.Current.FilePath), client.Current.FileLine)
+	}
+	monitor.history = append(monitor.history, MonitorRecord{
+		Stop:      client.Current.Stops,
+		Location:  location,
+		Goroutine: client.Current.GoroutineID,
+		Value:     value,
+	})
+	if len(monitor.history) > maxMonitorHistory {
+		monitor.history = monitor.history[len(monitor.history)-maxMonitorHistory:]
+	}
+}
+
+// find 根据编号（可以带 #）或者名字找到监视器，也可以直接使用表达式
+func (m *Monitors) find(ref string) (*Monitor, error) {
+	if id, err := strconv.Atoi(strings.TrimPrefix(ref, "#")); err == nil {
+		for _, monitor := range *m {
+			if monitor.id == id {
+				return monitor, nil
+			}
+		}
+		return nil, fmt.Errorf("monitor %d does not exist", id)
+	}
+	for _, monitor := range *m {
+		if monitor.name == ref {
+			return monitor, nil
+		}
+	}
+	if monitor, ok := (*m)[ref]; ok {
+		return monitor, nil
+	}
+	return nil, fmt.Errorf("monitor %s does not exist", ref)
+}
+
+// remove 删除监视器以及它的硬件观察点，编号不会再被使用
+func (m *Monitors) remove(ref string) error {
+	monitor, err := m.find(ref)
+	if err != nil {
+		return err
+	}
+	monitor.clearWatchpoint()
+	delete(*m, monitor.binding.String())
+	return nil
+}
+
+// clear 删除所有的监视器
+func (m *Monitors) clear() {
+	for key, monitor := range *m {
+		monitor.clearWatchpoint()
+		delete(*m, key)
+	}
+}
+
+// rename 给监视器起一个名字，之后可以用名字代替编号
+// 名字不能是数字或者以 # 开头，避免和编号混淆
+func (m *Monitors) rename(ref string, name string) error {
+	monitor, err := m.find(ref)
+	if err != nil {
+		return err
+	}
+	if _, err := strconv.Atoi(name); err == nil || strings.HasPrefix(name, "#") {
+		return fmt.Errorf("invalid monitor name %s", name)
+	}
+	for _, other := range *m {
+		if other != monitor && other.name == name {
+			return fmt.Errorf("monitor name %s is already used by #%d", name, other.id)
+		}
+	}
+	monitor.name = name
+	return nil
+}
+
+// setEnabled 启用或停用监视器，停用的监视器不读取数据，也不会让程序停下
+func (m *Monitors) setEnabled(ref string, enabled bool) error {
+	monitor, err := m.find(ref)
+	if err != nil {
+		return err
+	}
+	if monitor.disabled == !enabled {
+		return nil
+	}
+	monitor.disabled = !enabled
+	if !enabled {
+		monitor.clearWatchpoint()
+		return nil
+	}
+	// 重新启用时不把停用期间的变化当作变化
+	monitor.data = ""
+	monitor.status = ""
+	monitor.isChanged = false
+	return nil
+}
+
+// historyData 显示监视器的历史记录，数值类型还会显示变化的趋势
+func (m *Monitors) historyData(ref string) ([]string, error) {
+	monitor, err := m.find(ref)
+	if err != nil {
+		return nil, err
+	}
+	return FormatMonitorHistory(fmt.Sprintf("#%d %s  %s", monitor.id, tview.Escape(monitor.label()), monitor.display), monitor.history), nil
+}
+
+// FormatMonitorHistory 把历史记录显示为表格，值都是数时在最后显示 sparkline
+func FormatMonitorHistory(title string, history []MonitorRecord) []string {
+	result := []string{title}
+	if len(history) == 0 {
+		return append(result, "还没有记录")
+	}
+	result = append(result, fmt.Sprintf("%-6s %-9s %-40s %s", "stop", "goroutine", "location", "value"))
+	values := make([]float64, 0, len(history))
+	for _, record := range history {
+		goroutine := "-"
+		if record.Goroutine > 0 {
+			goroutine = fmt.Sprintf("%d", record.Goroutine)
+		}
+		result = append(result, fmt.Sprintf("%-6d %-9s %-40s %s", record.Stop, goroutine, record.Location, record.Value))
+		if value, ok := numericValue(record.Value); ok {
+			values = append(values, value)
+		}
+	}
+	if len(values) == len(history) && len(values) > 1 {
+		result = append(result, "[yellow]; "+Sparkline(values)+"[white]")
+	}
+	return result
+}
+
+// numericValue 把显示的值转换成数，十六进制、整数、浮点数和布尔值都可以
+func numericValue(value string) (float64, bool) {
+	switch value {
+	case "true":
+		return 1, true
+	case "false":
+		return 0, true
+	}
+	if strings.HasPrefix(value, "0x") && !strings.Contains(value, " ") {
+		n, err := strconv.ParseUint(value[2:], 16, 64)
+		return float64(n), err == nil
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	return n, err == nil && !math.IsNaN(n) && !math.IsInf(n, 0)
+}
+
+// sparkTicks 是 sparkline 使用的字符，从低到高
+var sparkTicks = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline 用一行字符显示一组数的变化趋势
+func Sparkline(values []float64) string {
+	if len(values) == 0 {
+		return ""
+	}
+	low, high := values[0], values[0]
+	for _, value := range values {
+		low = math.Min(low, value)
+		high = math.Max(high, value)
+	}
+	result := make([]rune, 0, len(values))
+	for _, value := range values {
+		index := 0
+		if high > low {
+			index = int((value - low) / (high - low) * float64(len(sparkTicks)-1))
+		}
+		result = append(result, sparkTicks[index])
+	}
+	return string(result)
+}
+
+func (m *Monitors) add(binding Binding, display DisplayType, isBreakpoint bool, condition *MonitorCondition) {
+	if monitor, ok := (*m)[binding.String()]; ok {
+		// 已经存在的监视器只更新显示类型和停下的条件，保留编号和历史记录
+		if monitor.display != display {
+			monitor.clearWatchpoint()
+		}
+		monitor.display = display
+		monitor.data = ""
+		monitor.isBreakpoint = isBreakpoint
+		monitor.condition = condition
+		return
+	}
+	(*m)[binding.String()] = &Monitor{
+		id:           nextMonitorID,
+		binding:      binding,
+		display:      display,
 		data:         "",
-		isBreakpoint: false,
+		isBreakpoint: isBreakpoint,
+		condition:    condition,
 		isChanged:    false,
 	}
+	nextMonitorID++
 }
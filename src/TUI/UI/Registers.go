@@ -0,0 +1,193 @@
+package UI
+
+import (
+	"fmt"
+	"github.com/go-delve/delve/service/api"
+	"strconv"
+	"strings"
+)
+
+// registerGroup 是寄存器面板中的一个分组
+type registerGroup struct {
+	title string
+	match func(name string) bool
+	// telescope 表示是否显示寄存器的值指向的内容
+	telescope bool
+}
+
+// registerGroups 是寄存器面板的分组，按顺序显示，向量寄存器在单独的面板中显示
+var registerGroups = []registerGroup{
+	{"通用寄存器", func(name string) bool {
+		switch name {
+		case "rip", "rsp", "rax", "rbx", "rcx", "rdx", "rsi", "rdi", "rbp":
+			return true
+		}
+		return len(name) <= 3 && strings.HasPrefix(name, "r") && name[1] >= '0' && name[1] <= '9'
+	}, true},
+	{"标志寄存器", func(name string) bool {
+		return name == "rflags"
+	}, false},
+	{"段寄存器", func(name string) bool {
+		switch name {
+		case "es", "cs", "ss", "ds", "fs", "gs":
+			return true
+		}
+		return false
+	}, false},
+	{"段基址", func(name string) bool {
+		return name == "fs_base" || name == "gs_base"
+	}, false},
+	{"x87", func(name string) bool {
+		switch name {
+		case "cw", "sw", "tw", "fop":
+			return true
+		}
+		return strings.HasPrefix(name, "st(")
+	}, false},
+	{"MXCSR", func(name string) bool {
+		return strings.HasPrefix(name, "mxcsr")
+	}, false},
+	{"其他", func(name string) bool {
+		return !isVectorRegister(name)
+	}, false},
+}
+
+// rflagsBits 是 RFLAGS 中单个位的标志
+var rflagsBits = []struct {
+	bit  uint
+	name string
+}{
+	{0, "CF"}, {2, "PF"}, {4, "AF"}, {6, "ZF"}, {7, "SF"}, {8, "TF"}, {9, "IF"}, {10, "DF"}, {11, "OF"},
+	{14, "NT"}, {16, "RF"}, {17, "VM"}, {18, "AC"}, {19, "VIF"}, {20, "VIP"}, {21, "ID"},
+}
+
+// mxcsrBits 是 MXCSR 中单个位的标志
+var mxcsrBits = []struct {
+	bit  uint
+	name string
+}{
+	{0, "IE"}, {1, "DE"}, {2, "ZE"}, {3, "OE"}, {4, "UE"}, {5, "PE"}, {6, "DAZ"},
+	{7, "IM"}, {8, "DM"}, {9, "ZM"}, {10, "OM"}, {11, "UM"}, {12, "PM"}, {15, "FZ"},
+}
+
+// mxcsrRoundingModes 是 MXCSR 中 RC 字段表示的舍入方式
+var mxcsrRoundingModes = []string{"nearest", "down", "up", "zero"}
+
+// isVectorRegister 判断是不是向量寄存器
+func isVectorRegister(name string) bool {
+	return strings.HasPrefix(name, "xmm") || strings.HasPrefix(name, "ymm") || strings.HasPrefix(name, "zmm")
+}
+
+// DecodeRflags 把 RFLAGS 解码成标志位的名字，例如 [PF ZF IF IOPL=0]
+func DecodeRflags(value uint64) string {
+	names := make([]string, 0)
+	for _, flag := range rflagsBits {
+		if value&(1<<flag.bit) != 0 {
+			names = append(names, flag.name)
+		}
+	}
+	names = append(names, fmt.Sprintf("IOPL=%d", (value>>12)&3))
+	return "[" + strings.Join(names, " ") + "]"
+}
+
+// DecodeMxcsr 把 MXCSR 解码成标志位的名字，例如 [IM DM ZM OM UM PM RC=nearest]
+func DecodeMxcsr(value uint64) string {
+	names := make([]string, 0)
+	for _, flag := range mxcsrBits {
+		if value&(1<<flag.bit) != 0 {
+			names = append(names, flag.name)
+		}
+	}
+	names = append(names, "RC="+mxcsrRoundingModes[(value>>13)&3])
+	return "[" + strings.Join(names, " ") + "]"
+}
+
+// registerValueString 得到寄存器显示的值，标志寄存器和 MXCSR 会解码
+func registerValueString(reg api.Register) string {
+	fields := strings.Fields(reg.Value)
+	if len(fields) == 0 {
+		return ""
+	}
+	switch strings.ToLower(reg.Name) {
+	case "rflags":
+		value, err := strconv.ParseUint(fields[0], 0, 64)
+		if err == nil {
+			return fmt.Sprintf("0x%x  %s", value, DecodeRflags(value))
+		}
+	case "mxcsr":
+		value, err := strconv.ParseUint(fields[0], 0, 64)
+		if err == nil {
+			return fmt.Sprintf("0x%x  %s", value, DecodeMxcsr(value))
+		}
+	}
+	return strings.Join(fields, " ")
+}
+
+// RegsToStrings 按照分组格式化寄存器，changed 中的寄存器标红（Rip 除外），通用寄存器显示指向的内容
+func RegsToStrings(regs api.Registers, changed map[string]bool) []string {
+	result := make([]string, 0, len(regs)+len(registerGroups))
+	used := make(map[string]bool)
+	for _, group := range registerGroups {
+		lines := make([]string, 0)
+		for _, reg := range regs {
+			name := strings.ToLower(reg.Name)
+			if used[name] || !group.match(name) {
+				continue
+			}
+			used[name] = true
+			line := fmt.Sprintf("%-7s %s", reg.Name, registerValueString(reg))
+			if changed[reg.Name] && name != "rip" {
+				line = fmt.Sprintf("[red]%s[white]", line)
+			}
+			if fields := strings.Fields(reg.Value); group.telescope && len(fields) > 0 {
+				value, err := strconv.ParseUint(fields[0], 0, 64)
+				if err == nil {
+					if annotation := Telescope(value); annotation != "" {
+						line += "  [yellow]" + annotation + "[white]"
+					}
+				}
+			}
+			lines = append(lines, line)
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		result = append(result, fmt.Sprintf("[yellow]; %s[white]", group.title))
+		result = append(result, lines...)
+	}
+	return result
+}
+
+// RegisterBaseline 记录寄存器在上一次停下时的值，用于找出这一次停下时发生变化的寄存器
+// 基准按照停下的次数切换，同一次停下中多次刷新（例如 regs、frame、so 显示返回值）不会清掉标红
+type RegisterBaseline struct {
+	// stop 是 current 对应的停下的次数
+	stop     int
+	previous map[string]string
+	current  map[string]string
+}
+
+// registerBaseline 是寄存器面板使用的基准
+var registerBaseline = &RegisterBaseline{}
+
+// Changed 和上一次停下时的寄存器比较，找出值发生变化的寄存器，stop 是当前停下的次数
+func (b *RegisterBaseline) Changed(stop int, regs api.Registers) map[string]bool {
+	if b.current != nil && stop != b.stop {
+		b.previous = b.current
+	}
+	b.stop = stop
+	b.current = make(map[string]string, len(regs))
+	changed := make(map[string]bool)
+	for _, reg := range regs {
+		b.current[reg.Name] = reg.Value
+		if old, ok := b.previous[reg.Name]; ok && old != reg.Value {
+			changed[reg.Name] = true
+		}
+	}
+	return changed
+}
+
+// changedRegisters 找出这一次停下时发生变化的寄存器
+func changedRegisters(regs api.Registers) map[string]bool {
+	return registerBaseline.Changed(client.Current.Stops, regs)
+}
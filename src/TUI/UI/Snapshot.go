@@ -0,0 +1,112 @@
+package UI
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// Snapshot 保存某一时刻一段内存的数据
+type Snapshot struct {
+	address uint64
+	data    []byte
+}
+
+type Snapshots map[string]*Snapshot
+
+var snapshots = NewSnapshots()
+
+func NewSnapshots() Snapshots {
+	return make(Snapshots)
+}
+
+// add 分段读取从 address 开始的 size 个字节，保存为 name，已经存在的会被覆盖
+func (s *Snapshots) add(name string, address uint64, size int) error {
+	if size <= 0 || size > maxExamineBytes {
+		return fmt.Errorf("invalid snapshot size 0x%x, at most 0x%x", size, maxExamineBytes)
+	}
+	data, err := client.ReadMemory(address, size)
+	if err != nil {
+		return err
+	}
+	(*s)[name] = &Snapshot{
+		address: address,
+		data:    data,
+	}
+	return nil
+}
+
+// diff 重新读取 name 对应的内存，和保存的数据比较
+func (s *Snapshots) diff(name string) ([]string, error) {
+	snapshot, ok := (*s)[name]
+	if !ok {
+		return nil, fmt.Errorf("snapshot %s does not exist", name)
+	}
+	data, err := client.ReadMemory(snapshot.address, len(snapshot.data))
+	if err != nil {
+		return nil, err
+	}
+	return DiffMemory(snapshot.data, data, snapshot.address), nil
+}
+
+// getSnapshotsData 列出所有的快照
+func (s *Snapshots) getSnapshotsData() []string {
+	names := make([]string, 0, len(*s))
+	for name := range *s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	result := make([]string, 0, len(names))
+	for _, name := range names {
+		snapshot := (*s)[name]
+		result = append(result, fmt.Sprintf("%s  0x%x  0x%x bytes", name, snapshot.address, len(snapshot.data)))
+	}
+	return result
+}
+
+// DiffMemory 比较同一段内存前后的数据
+// 先按照每行 16 个字节显示新的数据，变化的字节标红，再列出变化的 8 字节的值
+func DiffMemory(old, new []byte, start uint64) []string {
+	result := make([]string, 0, len(new)/16+2)
+	for offset := 0; offset < len(new); offset += 16 {
+		end := offset + 16
+		if end > len(new) {
+			end = len(new)
+		}
+		line := fmt.Sprintf("0x%x    ", start+uint64(offset))
+		for i := offset; i < end; i++ {
+			if old[i] != new[i] {
+				line += fmt.Sprintf("[red]%02x[white] ", new[i])
+			} else {
+				line += fmt.Sprintf("%02x ", new[i])
+			}
+		}
+		result = append(result, line[:len(line)-1])
+	}
+
+	changed := make([]string, 0)
+	for offset := 0; offset < len(new); offset += 8 {
+		end := offset + 8
+		if end > len(new) {
+			end = len(new)
+		}
+		if string(old[offset:end]) == string(new[offset:end]) {
+			continue
+		}
+		changed = append(changed, fmt.Sprintf("0x%x    0x%x -> [red]0x%x[white]",
+			start+uint64(offset), littleEndianValue(old[offset:end]), littleEndianValue(new[offset:end])))
+	}
+	if len(changed) == 0 {
+		result = append(result, "[yellow]; 没有变化[white]")
+		return result
+	}
+	result = append(result, fmt.Sprintf("[yellow]; %d 个 qword 发生了变化[white]", len(changed)))
+	return append(result, changed...)
+}
+
+// littleEndianValue 把至多 8 个字节按照小端序转换成整数
+func littleEndianValue(data []byte) uint64 {
+	buf := make([]byte, 8)
+	copy(buf, data)
+	return binary.LittleEndian.Uint64(buf)
+}
@@ -0,0 +1,92 @@
+package UI
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/rivo/tview"
+	"strconv"
+	"strings"
+)
+
+// telescopeDepth 是解引用链的最大深度，0 表示不显示指向的内容
+var telescopeDepth = 3
+
+// minPointer 小于这个值的数不当作指针
+const minPointer = 0x10000
+
+// minStringLength 至少有这么多个可打印字符才当作字符串显示
+const minStringLength = 4
+
+// maxStringLength 字符串最多显示的长度
+const maxStringLength = 32
+
+// setTelescopeDepth 设置解引用链的最大深度
+func setTelescopeDepth(depth string) error {
+	n, err := strconv.Atoi(depth)
+	if err != nil {
+		return err
+	}
+	if n < 0 {
+		return fmt.Errorf("invalid telescope depth %d", n)
+	}
+	telescopeDepth = n
+	return nil
+}
+
+// PrintableString 如果 data 以可打印字符开头，返回带引号的字符串
+func PrintableString(data []byte) (string, bool) {
+	end := 0
+	for end < len(data) && end < maxStringLength && data[end] >= 0x20 && data[end] < 0x7f {
+		end++
+	}
+	if end < minStringLength {
+		return "", false
+	}
+	s := strconv.Quote(string(data[:end]))
+	if end == maxStringLength && end < len(data) {
+		s += "..."
+	}
+	return s, true
+}
+
+// Telescope 得到 value 指向的内容
+// 指向代码的显示为 symbol+off，指向数据的显示为解引用链，例如 -> 0xc000020000 -> "hello"
+// 不是指针时返回空字符串
+func Telescope(value uint64) string {
+	if client == nil || telescopeDepth == 0 {
+		return ""
+	}
+	sb := strings.Builder{}
+	for depth := 0; depth < telescopeDepth && value >= minPointer; depth++ {
+		if name, offset, err := client.FunctionOfAddress(value); err == nil {
+			sb.WriteString(fmt.Sprintf(" -> %s+0x%x", name, offset))
+			break
+		}
+		data, err := client.ExamineMemory(value, maxStringLength)
+		if err != nil {
+			// 没有映射的地址
+			break
+		}
+		if s, ok := PrintableString(data); ok {
+			sb.WriteString(" -> " + tview.Escape(s))
+			break
+		}
+		value = binary.LittleEndian.Uint64(data)
+		sb.WriteString(fmt.Sprintf(" -> 0x%x", value))
+	}
+	return strings.TrimPrefix(sb.String(), " ")
+}
+
+// FormatStack 格式化栈上的数据，每行一个 8 字节的值，并显示它指向的内容
+func FormatStack(mems []byte, start uint64) []string {
+	result := make([]string, 0, len(mems)/8)
+	for offset := 0; offset+8 <= len(mems); offset += 8 {
+		value := binary.LittleEndian.Uint64(mems[offset:])
+		line := fmt.Sprintf("0x%x    0x%016x", start+uint64(offset), value)
+		if annotation := Telescope(value); annotation != "" {
+			line += "  [yellow]" + annotation + "[white]"
+		}
+		result = append(result, line)
+	}
+	return result
+}
@@ -1,13 +1,17 @@
 package UI
 
 import (
-	"MyDebugger/src/utils"
 	"fmt"
+	"sort"
 )
 
 type Tracker struct {
+	binding   Binding
+	address   uint64
+	display   DisplayType
 	data      string
-	size      int
+	old       string
+	status    string
 	isChanged bool
 }
 
@@ -19,31 +23,37 @@ func NewTrackers() Trackers {
 	return make(Trackers)
 }
 
-func (t *Trackers) add(address string, size int) {
-	if _, ok := (*t)[address]; ok {
+func (t *Trackers) add(binding Binding, display DisplayType) {
+	if _, ok := (*t)[binding.String()]; ok {
 		return
 	}
-	u64Address, err := utils.StringToUint64(address)
-	if err != nil {
-		return
-	}
-	d, err := client.GetDataFromAddress(u64Address, size)
-	if err != nil {
-		return
-	}
-	(*t)[address] = &Tracker{
-		size:      size,
-		data:      d,
+	address, data, status := resolveValue(binding, display)
+	(*t)[binding.String()] = &Tracker{
+		binding:   binding,
+		address:   address,
+		display:   display,
+		data:      data,
+		status:    status,
 		isChanged: false,
 	}
 }
 
 func (t *Trackers) getTrackersData() []string {
 	result := make([]string, 0)
-	for addr, tracker := range *t {
-		line := fmt.Sprintf("%s  %s", addr, tracker.data)
+	keys := make([]string, 0, len(*t))
+	for key := range *t {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		tracker := (*t)[key]
+		if tracker.status != "" {
+			result = append(result, fmt.Sprintf("[gray]%s  %s[white]", key, tracker.status))
+			continue
+		}
+		line := fmt.Sprintf("%s  0x%x  %s  %s", key, tracker.address, tracker.display, tracker.data)
 		if tracker.isChanged {
-			line = fmt.Sprintf("[red]%s[white]", line)
+			line = fmt.Sprintf("[red]%s  0x%x  %s  %s -> %s[white]", key, tracker.address, tracker.display, tracker.old, tracker.data)
 			tracker.isChanged = false
 		}
 		result = append(result, line)
@@ -51,22 +61,34 @@ func (t *Trackers) getTrackersData() []string {
 	return result
 }
 
+// track 重新计算每个跟踪器的地址并读取数据，地址没变而数据变了的时候返回 true
 func (t *Trackers) track() bool {
 	flag := false
-	for addr, tracker := range trackers {
-		d, err := client.GetDataFromStringAddress(addr, tracker.size)
-		if err != nil {
-			return flag
-		}
-		if d != tracker.data {
+	for _, tracker := range *t {
+		address, data, status := resolveValue(tracker.binding, tracker.display)
+		if status == "" && tracker.status == "" && tracker.address == address && tracker.data != data {
 			tracker.isChanged = true
-			tracker.data = d
+			tracker.old = tracker.data
 			flag = true
 		}
+		tracker.address, tracker.data, tracker.status = address, data, status
 	}
 	return flag
 }
 
-func (t *Trackers) remove(address string) {
-	delete(*t, address)
+// reset 重新开始调试程序之后，重新计算地址和数据
+func (t *Trackers) reset() {
+	for _, tracker := range *t {
+		tracker.address, tracker.data, tracker.status = resolveValue(tracker.binding, tracker.display)
+		tracker.isChanged = false
+	}
+}
+
+// remove 删除跟踪器，name 可以是表达式，也可以是带 @函数名 的完整名字
+func (t *Trackers) remove(name string) {
+	for key, tracker := range *t {
+		if key == name || tracker.binding.Expr == name {
+			delete(*t, key)
+		}
+	}
 }
@@ -0,0 +1,91 @@
+package UI
+
+import (
+	"debug/dwarf"
+	"fmt"
+	"github.com/go-delve/delve/service/api"
+	"github.com/rivo/tview"
+	"reflect"
+	"strings"
+)
+
+// variableValue 得到变量在一行中显示的值
+func variableValue(variable api.Variable) string {
+	if variable.Unreadable != "" {
+		return fmt.Sprintf("(unreadable %s)", variable.Unreadable)
+	}
+	return tview.Escape(variable.SinglelineString())
+}
+
+// fieldVariable 找到字段对应的 delve 变量，delve 的字段和 DWARF 中的顺序一致，顺序对不上时按名字查找
+func fieldVariable(variable api.Variable, index int, name string) (api.Variable, bool) {
+	if index < len(variable.Children) && variable.Children[index].Name == name {
+		return variable.Children[index], true
+	}
+	for _, child := range variable.Children {
+		if child.Name == name {
+			return child, true
+		}
+	}
+	return api.Variable{}, false
+}
+
+// FormatTypedValue 按照类型的布局以树的形式显示变量，每个字段前面是相对起始地址的偏移和大小
+// 字段之间的填充单独显示一行，嵌套的结构体会展开
+func FormatTypedValue(variable api.Variable, t dwarf.Type) []string {
+	layout := LayoutOf(t)
+	result := []string{fmt.Sprintf("0x%x  %s  size %d", variable.Addr, tview.Escape(layout.Name), layout.Size)}
+	if len(layout.Fields) == 0 || variable.Kind != reflect.Struct {
+		return append(result, "  = "+variableValue(variable))
+	}
+	return append(result, formatFields(variable, layout, 0, 1)...)
+}
+
+// formatFields 显示结构体的每个字段，base 是结构体相对起始地址的偏移，depth 是缩进的层数
+func formatFields(variable api.Variable, layout TypeLayout, base int64, depth int) []string {
+	result := make([]string, 0, len(layout.Fields))
+	indent := strings.Repeat("  ", depth)
+	for i, field := range layout.Fields {
+		offset := base + field.Offset
+		prefix := fmt.Sprintf("%s+0x%-4x %-4d %s %s", indent, offset, field.Size, field.Name, tview.Escape(field.Type))
+		child, ok := fieldVariable(variable, i, field.Name)
+		switch {
+		case !ok:
+			result = append(result, prefix)
+		case child.Kind == reflect.Struct && len(child.Children) > 0:
+			// string 和切片在 DWARF 中也是结构体，只展开 delve 认为是结构体的字段
+			result = append(result, prefix)
+			result = append(result, formatFields(child, LayoutOf(field.typ), offset, depth+1)...)
+		default:
+			result = append(result, prefix+" = "+variableValue(child))
+		}
+		if field.Padding > 0 {
+			result = append(result, fmt.Sprintf("[yellow]%s+0x%-4x %-4d (padding)[white]", indent, offset+field.Size, field.Padding))
+		}
+	}
+	return result
+}
+
+// typeForView 得到用来显示的类型，*T 表示 address 是指向 T 的指针，和 T 一样显示 T
+func typeForView(typeName string) string {
+	return strings.TrimPrefix(typeName, "*")
+}
+
+// ViewAs 把 address 处的内存当作 typeName 类型显示
+func (info *viewInfo) ViewAs(address uint64, typeName string) error {
+	typeName = typeForView(typeName)
+	types, err := targetTypes()
+	if err != nil {
+		return err
+	}
+	t, err := types.Lookup(typeName)
+	if err != nil {
+		return err
+	}
+	variable, err := client.EvalAddressAs(address, typeName)
+	if err != nil {
+		return err
+	}
+	info.data = FormatTypedValue(*variable, t)
+	return nil
+}
@@ -3,6 +3,7 @@ package UI
 import (
 	MyApi "MyDebugger/src/api"
 	"context"
+	"fmt"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 	"golang.org/x/sync/errgroup"
@@ -57,8 +58,45 @@ func (ui *UI) flashUI() error {
 }
 
 // dealWithEnter 当输入回车键之后，处理输入的指令
+// SplitCommand 按空格把命令分成参数，括号和引号中的空格不分隔参数
+// 所以 x/4xg ($rsp + 8) 中的表达式是一个参数，--when '> 100' 中的条件也是一个参数（保留引号）
+func SplitCommand(command string) []string {
+	args := make([]string, 0)
+	var current strings.Builder
+	depth := 0
+	var quote rune
+	for _, c := range command {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(' || c == '[':
+			depth++
+		case (c == ')' || c == ']') && depth > 0:
+			depth--
+		case (c == ' ' || c == '\t') && depth == 0:
+			if current.Len() > 0 {
+				args = append(args, current.String())
+				current.Reset()
+			}
+			continue
+		}
+		current.WriteRune(c)
+	}
+	if current.Len() > 0 {
+		args = append(args, current.String())
+	}
+	return args
+}
+
 func (ui *UI) dealWithEnter(command string) error {
-	tmp := strings.Split(command, " ")
+	tmp := SplitCommand(command)
+	if len(tmp) == 0 {
+		return nil
+	}
 	cmd := tmp[0]
 	var args []string
 	if len(tmp) > 1 {
@@ -66,6 +104,14 @@ func (ui *UI) dealWithEnter(command string) error {
 	} else {
 		args = nil
 	}
+	// x/10xg 这样的命令，/ 之后的部分作为第一个参数
+	if index := strings.Index(cmd, "/"); index > 0 {
+		args = append([]string{cmd[index:]}, args...)
+		cmd = cmd[:index]
+	}
+	if running != nil && !liveCommands[cmd] {
+		return errRunning
+	}
 	if command, ok := Commands[cmd]; ok {
 		return command.handler(args)
 	}
@@ -104,6 +150,45 @@ func (ui *UI) dealWithCommand(key tcell.Key) {
 	// todo: add history
 }
 
+// dealWithDisassemblyKey 处理聚焦在反汇编窗口时的按键
+// 上下键移动光标，u 运行到光标所在的指令处
+func (ui *UI) dealWithDisassemblyKey(event *tcell.EventKey) *tcell.EventKey {
+	view, ok := ui.views["first"]
+	if !ok {
+		return event
+	}
+	switch {
+	case event.Key() == tcell.KeyUp:
+		view.moveCursor(-1)
+	case event.Key() == tcell.KeyDown:
+		view.moveCursor(1)
+	case event.Key() == tcell.KeyRune && event.Rune() == 'u':
+		address, ok := view.cursorAddress()
+		if !ok {
+			return nil
+		}
+		if running != nil {
+			ui.errChannel <- errRunning
+			return nil
+		}
+		view.cursor = -1
+		err := ui.until([]string{fmt.Sprintf("0x%x", address)})
+		if err != nil {
+			ui.errChannel <- err
+		}
+		err = ui.flashUI()
+		if err != nil {
+			ui.errChannel <- err
+		}
+		ui.MonitorDataChanged()
+		return nil
+	default:
+		return event
+	}
+	_ = view.setTextView()
+	return nil
+}
+
 // MonitorError 监控 error 信息，显示在 TUI 上
 // 其实在 dealWithCommand 里捕获 error 后调用也行，但是想试试 channel，练手
 func (ui *UI) MonitorError() {
@@ -116,7 +201,13 @@ func (ui *UI) MonitorError() {
 
 // MonitorDataChanged 当监控的数据发生变化的时候，显示在 TUI 上
 func (ui *UI) MonitorDataChanged() {
-	if monitors.monitorAddress() {
+	if running != nil {
+		// 程序在后台运行，由 runLive 刷新
+		return
+	}
+	// 执行命令时检查 --break 的监视器已经读取过一次，那时发现的变化也要显示
+	changed := monitors.takeChanged()
+	if monitors.monitorAddress() || changed {
 		ui.MonitorView2()
 	}
 }
@@ -136,11 +227,39 @@ func initCommands(ui *UI) {
 	}
 	disassembleCommand := &CommandInfo{
 		handler:  ui.disassembly,
-		helpInfo: "d/disassemble <address>: 查看 address 处的汇编",
+		helpInfo: "d/disassemble <address/function>: 查看 address 处的汇编（address 可以是表达式，例如 $rip+0x10），或者 function 完整的汇编（标注序言和尾声）",
 	}
 	examineMemoryCommand := &CommandInfo{
 		handler:  ui.examineMemory,
-		helpInfo: "x (flag) <address>: 查看 address 处的值",
+		helpInfo: "x/<count><format><size> <address>: 查看 address 处的值，format 为 x/d/u/o/t/c/f/a/s/i，size 为 b/h/w/g，没有指定时沿用上一次的，address 中有空格时要用括号括起来，例如 x/4xg ($rsp + 8)",
+	}
+	findCommand := &CommandInfo{
+		handler:  ui.find,
+		helpInfo: "find <start> <end/+len> <pattern>: 在内存中查找，pattern 可以是 \"str\"、u\"str\"（UTF-16）、b:/h:/w:/g:<数字>、0x 开头的指针，或者 48 8b ?? 05 这样的字节序列（?? 是通配符）",
+	}
+	snapshotCommand := &CommandInfo{
+		handler:  ui.snapshot,
+		helpInfo: "snapshot [name address len]: 保存 address 开始的 len 个字节，没有参数时列出所有的快照",
+	}
+	diffCommand := &CommandInfo{
+		handler:  ui.diff,
+		helpInfo: "diff <name>: 重新读取快照对应的内存，标出变化的字节，并列出变化的 qword",
+	}
+	dumpMemoryCommand := &CommandInfo{
+		handler:  ui.dumpMemory,
+		helpInfo: "dump-memory <address> <len> <file> [raw/hex/go]: 把 address 开始的 len 个字节保存到 file，默认保存原始的字节，len 最多 0x1000000",
+	}
+	loadMemoryCommand := &CommandInfo{
+		handler:  ui.loadMemory,
+		helpInfo: "load-memory <file> <address>: 把 file 的内容写入 address 开始的内存，file 最多 0x10000 个字节",
+	}
+	viewAsCommand := &CommandInfo{
+		handler:  ui.viewAs,
+		helpInfo: "view <address> as <type>: 把 address 处的内存当作 Go 类型显示，显示每个字段的偏移、大小和填充，*T 表示 address 是指向 T 的指针",
+	}
+	layoutCommand := &CommandInfo{
+		handler:  ui.layout,
+		helpInfo: "layout <type>: 显示 Go 类型每个字段的偏移、大小、对齐和填充，以及填充最少的字段顺序",
 	}
 	runCommand := &CommandInfo{
 		handler:  ui.run,
@@ -156,27 +275,39 @@ func initCommands(ui *UI) {
 	}
 	nextCommand := &CommandInfo{
 		handler:  ui.next,
-		helpInfo: "n/next: 单步执行，但不进入函数内（源码层面）",
+		helpInfo: "n/next [count]: 单步执行 count 次，但不进入函数内（源码层面）",
 	}
 	nextInCommand := &CommandInfo{
 		handler:  ui.nextIn,
-		helpInfo: "ni/next-in: 单步执行，但不进入函数内（汇编层面）",
+		helpInfo: "ni/next-in [count]: 单步执行 count 次，但不进入函数内（汇编层面）",
 	}
 	stepOutCommand := &CommandInfo{
 		handler:  ui.stepOut,
-		helpInfo: "so/step-out: 跳出当前函数",
+		helpInfo: "so/step-out [count]: 跳出当前函数，重复 count 次",
 	}
 	stepInCommand := &CommandInfo{
 		handler:  ui.stepIn,
-		helpInfo: "si/step-in: 单步执行，进入函数内",
+		helpInfo: "si/step-in [count]: 单步执行 count 次，进入函数内（汇编层面），进入跳过列表中的函数时自动跳出，它回调的用户代码（例如 fmt 调用的 String 方法）也会被跳过，需要时请在回调中下断点",
+	}
+	stepCommand := &CommandInfo{
+		handler:  ui.step,
+		helpInfo: "s/step [count]: 单步执行 count 次，进入函数内（源码层面），进入跳过列表中的函数时自动跳出，它回调的用户代码（例如 fmt 调用的 String 方法）也会被跳过，需要时请在回调中下断点",
+	}
+	skipCommand := &CommandInfo{
+		handler:  ui.skip,
+		helpInfo: "skip [list|add <pattern>|remove <pattern>|clear|reset]: 管理 step 时跳过的函数，pattern 可以是 std（标准库）、包路径的 glob，或者 file:<文件 glob>",
 	}
 	continueCommand := &CommandInfo{
 		handler:  ui.continues,
-		helpInfo: "c/continue: 执行至下一个断点处",
+		helpInfo: "c/continue [&]: 执行至下一个断点处；c & 在后台运行，期间每隔一段时间（monitor interval 设置）短暂停下刷新监视器，halt 停下",
+	}
+	haltCommand := &CommandInfo{
+		handler:  ui.halt,
+		helpInfo: "halt: 让 c & 在后台运行的程序停下",
 	}
 	createBreakpointCommand := &CommandInfo{
 		handler:  ui.createBreakpoint,
-		helpInfo: "b/break <address> (name): 在 address 处创建一个名为 name 的断点",
+		helpInfo: "b/break <address/function> (name): 在 address 处创建一个名为 name 的断点，address 可以是表达式，例如 *($rsp)",
 	}
 	quitCommand := &CommandInfo{
 		handler:  ui.quit,
@@ -184,7 +315,7 @@ func initCommands(ui *UI) {
 	}
 	helpCommand := &CommandInfo{
 		handler:  ui.viewHelp,
-		helpInfo: "h/help <command>: 查看某个指令的用法",
+		helpInfo: "h/help <command>: 查看某个指令的用法；参数之间用空格分隔，括号和引号中的空格不分隔参数，带空格的表达式要用括号括起来，例如 x/4xg ($rsp + 8)",
 	}
 	focusCommand := &CommandInfo{
 		handler:  ui.focus,
@@ -196,11 +327,43 @@ func initCommands(ui *UI) {
 	}
 	monitorCommand := &CommandInfo{
 		handler:  ui.monitor,
-		helpInfo: "m/monitor <address> <size>: 监视某个地址的值",
+		helpInfo: "m/monitor <expression> [size/type] [--global | --in <function>] [--break] [--when '<op> <value>']: 监视某个地址的值，type 可以是 i8-i64、u8-u64、f32、f64、bool、ptr、str:N 或者 Go 类型，例如 $rsp+0x20、*(u64*)($rbp-8)、&runtime.sched（有空格时要用括号括起来，例如 ($rbp - 8)），每次停下时重新计算地址，用到寄存器或局部变量的表达式只在当前函数中有效；--break 在 continue 和单步（包括 n 10 这样的多步）时值发生变化就停下，--when '> 100' 只在满足条件时停下（支持 == != < <= > >=），1/2/4/8 字节的使用硬件观察点，其他的在每次停下时检查；monitor list 列出所有监视器；monitor delete|enable|disable <id>... 删除、启用或停用监视器，monitor clear 删除全部，monitor rename <id> <name> 起名字之后可以用名字代替 id；monitor history <id> 显示值的变化记录；monitor interval <ms> 设置 c & 时刷新的间隔，0 表示不刷新",
+	}
+	registersCommand := &CommandInfo{
+		handler:  ui.registers,
+		helpInfo: "regs/registers: 在右上角显示寄存器",
+	}
+	vectorCommand := &CommandInfo{
+		handler:  ui.vector,
+		helpInfo: "vec/vector [<register/all> <hex/i8/i16/i32/i64/f32/f64>]: 在右上角显示向量寄存器，或者设置寄存器的 lane 格式",
+	}
+	threadsCommand := &CommandInfo{
+		handler:  ui.viewThreads,
+		helpInfo: "threads: 查看所有线程的 PC、所在函数和正在运行的协程",
+	}
+	threadCommand := &CommandInfo{
+		handler:  ui.thread,
+		helpInfo: "thread <id>: 切换到某个线程，显示该线程的寄存器和反汇编",
+	}
+	telescopeCommand := &CommandInfo{
+		handler:  ui.telescope,
+		helpInfo: "telescope <depth>: 设置寄存器和栈上的指针解引用的最大深度，0 表示不显示",
+	}
+	frameCommand := &CommandInfo{
+		handler:  ui.frame,
+		helpInfo: "frame [n]: 选择第 n 帧，在左下角显示栈帧布局（返回地址、保存的 rbp、局部变量和参数）",
+	}
+	memoryCommand := &CommandInfo{
+		handler:  ui.memory,
+		helpInfo: "mem/memory: 在左下角显示从 Rsp 开始的栈上的内存",
+	}
+	untilCommand := &CommandInfo{
+		handler:  ui.until,
+		helpInfo: "u/until <address/location>: 运行到某个位置，只对当前协程生效；聚焦反汇编窗口时按 u 运行到光标处",
 	}
 	trackCommand := &CommandInfo{
 		handler:  ui.track,
-		helpInfo: "tracker <action> <address> [size=4]: 跟踪地址处的值",
+		helpInfo: "track add <expression> [size/type=4] [--global | --in <function>] / track delete <expression> / track continue: 跟踪地址处的值，作用域和 monitor 一样",
 	}
 
 	Commands = map[string]*CommandInfo{
@@ -210,9 +373,13 @@ func initCommands(ui *UI) {
 		"b":                createBreakpointCommand,
 		"break":            createBreakpointCommand,
 		"c":                continueCommand,
+		"halt":             haltCommand,
 		"continue":         continueCommand,
 		"si":               stepInCommand,
 		"step-in":          stepInCommand,
+		"s":                stepCommand,
+		"step":             stepCommand,
+		"skip":             skipCommand,
 		"so":               stepOutCommand,
 		"step-out":         stepOutCommand,
 		"n":                nextCommand,
@@ -222,6 +389,13 @@ func initCommands(ui *UI) {
 		"r":                runCommand,
 		"run":              runCommand,
 		"x":                examineMemoryCommand,
+		"find":             findCommand,
+		"snapshot":         snapshotCommand,
+		"diff":             diffCommand,
+		"dump-memory":      dumpMemoryCommand,
+		"load-memory":      loadMemoryCommand,
+		"view":             viewAsCommand,
+		"layout":           layoutCommand,
 		"d":                disassembleCommand,
 		"disassemble":      disassembleCommand,
 		"lb":               listBreakpointCommand,
@@ -240,6 +414,18 @@ func initCommands(ui *UI) {
 		"m":                monitorCommand,
 		"monitor":          monitorCommand,
 		"track":            trackCommand,
+		"regs":             registersCommand,
+		"registers":        registersCommand,
+		"vec":              vectorCommand,
+		"vector":           vectorCommand,
+		"threads":          threadsCommand,
+		"thread":           threadCommand,
+		"telescope":        telescopeCommand,
+		"frame":            frameCommand,
+		"mem":              memoryCommand,
+		"memory":           memoryCommand,
+		"u":                untilCommand,
+		"until":            untilCommand,
 	}
 	wordList = getDicKeys(Commands)
 }
@@ -287,6 +473,8 @@ func InitUI(address string) (*UI, error) {
 		"fourth": NewTextViewInfo("调用栈", 1, 1, handler, focusHandler),
 	}
 
+	ui.views["first"].view.SetInputCapture(ui.dealWithDisassemblyKey)
+
 	ui.DisassemblyView()
 	ui.RegistersView()
 	ui.MemoryView()
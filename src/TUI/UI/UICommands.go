@@ -2,8 +2,10 @@ package UI
 
 import (
 	"MyDebugger/src/utils"
+	"fmt"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // command start
@@ -30,12 +32,12 @@ func (ui *UI) createBreakpoint(args []string) error {
 		return ui.viewHelp([]string{"b"})
 	}
 
-	if strings.HasPrefix(args[0], "0x") {
-		loc, err := utils.StringToUint64(args[0])
-		if err != nil {
-			return err
-		}
-		err = client.CreateBreakpointByAddress(loc, name)
+	address, isSymbol, err := parseLocation(args[0])
+	if err != nil {
+		return err
+	}
+	if !isSymbol {
+		err = client.CreateBreakpointByAddress(address, name)
 		if err != nil {
 			return err
 		}
@@ -51,7 +53,10 @@ func (ui *UI) createBreakpoint(args []string) error {
 
 // continues 执行到下一个断点处
 func (ui *UI) continues(args []string) error {
-	err := client.Continue()
+	if len(args) == 1 && args[0] == "&" {
+		return ui.continueLive()
+	}
+	err := ui.runWithMonitors(client.Continue)
 	if err != nil {
 		return err
 	}
@@ -60,34 +65,163 @@ func (ui *UI) continues(args []string) error {
 
 // stepIn 单步执行，进入函数内
 func (ui *UI) stepIn(args []string) error {
-	err := client.StepInstruction()
-	if err != nil {
-		return err
-	}
-	return ui.flashData()
+	return ui.repeatStep(func() error {
+		return client.StepSkipping(client.StepInstruction)
+	}, args)
+}
+
+// step 单步执行，进入函数内（源码层面）
+func (ui *UI) step(args []string) error {
+	return ui.repeatStep(func() error {
+		return client.StepSkipping(client.Step)
+	}, args)
 }
 
-// stepOut 跳出当前函数
+// stepOut 跳出当前函数，有返回值的话在右下角显示
 func (ui *UI) stepOut(args []string) error {
-	err := client.StepOut()
+	err := ui.repeatStep(client.StepOut, args)
 	if err != nil {
 		return err
 	}
+	if len(client.Current.ReturnValues) == 0 {
+		return nil
+	}
+	ui.ReturnValuesView()
 	return ui.flashData()
 }
 
 // next 单步执行，不进入函数（源码层面）
 func (ui *UI) next(args []string) error {
-	err := client.Next()
+	return ui.repeatStep(client.Next, args)
+}
+
+// nextIn 单步执行，不进入函数（汇编层面）
+func (ui *UI) nextIn(args []string) error {
+	return ui.repeatStep(client.NextInstruction, args)
+}
+
+// until 运行到某个位置，使用只对当前协程生效的一次性断点
+func (ui *UI) until(args []string) error {
+	if args == nil || len(args) != 1 {
+		return ui.viewHelp([]string{"until"})
+	}
+	err := client.RunUntil(args[0])
 	if err != nil {
 		return err
 	}
 	return ui.flashData()
 }
 
-// nextIn 单步执行，不进入函数（汇编层面）
-func (ui *UI) nextIn(args []string) error {
-	err := client.NextInstruction()
+// repeatStep 按照参数中的次数重复执行单步命令，中途不刷新界面
+// 执行多次时，结束后显示实际执行的次数
+func (ui *UI) repeatStep(step func() error, args []string) error {
+	count := 1
+	if len(args) > 0 {
+		var err error
+		count, err = strconv.Atoi(args[0])
+		if err != nil {
+			return err
+		}
+		if count < 1 {
+			return fmt.Errorf("invalid count %d", count)
+		}
+	}
+	// 每一步之后检查 --break 的监视器，满足条件时提前停止
+	triggered := false
+	executed, err := client.Repeat(func() error {
+		var err error
+		triggered, err = runUntilMonitors(step, false)
+		return err
+	}, count, func() bool {
+		return triggered
+	})
+	if triggered {
+		ui.MonitorView()
+	}
+	if flashErr := ui.flashData(); err == nil {
+		err = flashErr
+	}
+	if count == 1 {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("执行了 %d/%d 步: %w", executed, count, err)
+	}
+	if triggered {
+		// 右下角显示监视器的变化
+		return nil
+	}
+	info := fmt.Sprintf("执行了 %d/%d 步", executed, count)
+	if executed < count {
+		info += "，遇到断点停止"
+	}
+	ui.StepInfoView(info)
+	return nil
+}
+
+// skip 管理 step 时跳过的包和文件
+func (ui *UI) skip(args []string) error {
+	if len(args) == 0 {
+		ui.SkipListView()
+		return nil
+	}
+	switch args[0] {
+	case "list":
+	case "add":
+		if len(args) != 2 {
+			return ui.viewHelp([]string{"skip"})
+		}
+		err := client.AddSkipPattern(args[1])
+		if err != nil {
+			return err
+		}
+	case "remove":
+		if len(args) != 2 {
+			return ui.viewHelp([]string{"skip"})
+		}
+		err := client.RemoveSkipPattern(args[1])
+		if err != nil {
+			return err
+		}
+	case "clear":
+		client.ClearSkipPatterns()
+	case "reset":
+		client.ResetSkipPatterns()
+	default:
+		return ui.viewHelp([]string{"skip"})
+	}
+	ui.SkipListView()
+	return nil
+}
+
+// registers 在右上角显示通用寄存器等
+func (ui *UI) registers(args []string) error {
+	ui.RegistersView()
+	return ui.flashData()
+}
+
+// vector 在右上角显示向量寄存器，或者设置某个寄存器的 lane 格式
+func (ui *UI) vector(args []string) error {
+	switch len(args) {
+	case 0:
+	case 2:
+		err := setVectorFormat(args[0], args[1])
+		if err != nil {
+			return err
+		}
+	default:
+		return ui.viewHelp([]string{"vector"})
+	}
+	ui.VectorRegistersView()
+	return ui.flashData()
+}
+
+// telescope 设置寄存器和栈上的值解引用链的最大深度
+func (ui *UI) telescope(args []string) error {
+	if len(args) != 1 {
+		return ui.viewHelp([]string{"telescope"})
+	}
+	err := setTelescopeDepth(args[0])
 	if err != nil {
 		return err
 	}
@@ -152,56 +286,169 @@ func (ui *UI) run(args []string) error {
 	if err != nil {
 		return err
 	}
+	// 地址会变化，监视器和跟踪器重新计算
+	monitors.reset()
+	trackers.reset()
 	return ui.flashData()
 }
 
-// examineMemory 查看从某地址开始的内存数据
+// examineMemory 查看从某地址开始的内存数据，用法和 GDB 一样：x/<count><format><size> <address>
+// 为了兼容，也可以用空格分开：x <count><format><size> <address>
 func (ui *UI) examineMemory(args []string) error {
-	if args == nil || len(args) == 0 {
+	if len(args) != 1 && len(args) != 2 {
 		return ui.viewHelp([]string{"x"})
 	}
-	var mode uint64
-	var format string
-	var address uint64
+	spec := ExamineSpec{Format: lastExamine.Format, Size: lastExamine.Size}
 	var err error
-	if len(args) == 1 {
-		mode = 1
-		format = "hex"
-		address, err = utils.StringToUint64(args[0])
+	if len(args) == 2 {
+		spec, err = ParseExamineSpec(args[0], lastExamine)
 		if err != nil {
 			return err
 		}
-
-	} else if len(args) == 2 {
-		address, err = utils.StringToUint64(args[1])
+	}
+	address, err := EvaluateAddress(args[len(args)-1])
+	if err != nil {
+		return err
+	}
+	if view, ok := ui.views["third"]; ok {
+		err = view.ExamineMemory(address, spec)
 		if err != nil {
 			return err
 		}
-		if len(args[0]) == 1 {
-			mode = 1
-			format = getFormat(args[0][0])
-		} else if len(args[0]) == 2 {
-			mode = getMode(args[0][0])
-			format = getFormat(args[0][1])
-		} else {
-			// todo: return help error
-			return err
+	}
+	lastExamine = spec
+	return nil
+}
+
+// find 在一段内存中查找字节序列、字符串或者整数
+func (ui *UI) find(args []string) error {
+	if len(args) < 3 {
+		return ui.viewHelp([]string{"find"})
+	}
+	start, end, err := ParseSearchRange(args[0], args[1])
+	if err != nil {
+		return err
+	}
+	pattern, err := ParseSearchPattern(strings.Join(args[2:], " "))
+	if err != nil {
+		return err
+	}
+	if view, ok := ui.views["third"]; ok {
+		return view.FindMemory(start, end, pattern)
+	}
+	return nil
+}
+
+// snapshot 保存一段内存当前的数据，没有参数时列出所有的快照
+func (ui *UI) snapshot(args []string) error {
+	if len(args) == 0 {
+		if view, ok := ui.views["third"]; ok {
+			view.data = snapshots.getSnapshotsData()
 		}
-	} else {
-		// todo: return help error
+		return nil
+	}
+	if len(args) != 3 {
+		return ui.viewHelp([]string{"snapshot"})
+	}
+	address, err := EvaluateAddress(args[1])
+	if err != nil {
+		return err
+	}
+	size, err := EvaluateAddress(args[2])
+	if err != nil {
 		return err
 	}
+	return snapshots.add(args[0], address, int(size))
+}
 
+// diff 和快照比较，显示这段内存发生的变化
+func (ui *UI) diff(args []string) error {
+	if len(args) != 1 {
+		return ui.viewHelp([]string{"diff"})
+	}
+	data, err := snapshots.diff(args[0])
+	if err != nil {
+		return err
+	}
 	if view, ok := ui.views["third"]; ok {
-		err = view.ExamineMemory(address, mode, format)
-		if err != nil {
-			return err
-		}
+		view.data = data
 	}
 	return nil
 }
 
-// disassembly 查看从某地址开始的汇编代码
+// dumpMemory 把一段内存保存到文件
+func (ui *UI) dumpMemory(args []string) error {
+	if len(args) != 3 && len(args) != 4 {
+		return ui.viewHelp([]string{"dump-memory"})
+	}
+	address, err := EvaluateAddress(args[0])
+	if err != nil {
+		return err
+	}
+	size, err := EvaluateAddress(args[1])
+	if err != nil {
+		return err
+	}
+	format := "raw"
+	if len(args) == 4 {
+		format = args[3]
+	}
+	err = dumpMemory(address, int(size), args[2], format)
+	if err != nil {
+		return err
+	}
+	ui.StepInfoView(fmt.Sprintf("已将 0x%x 开始的 0x%x 个字节保存到 %s", address, size, args[2]))
+	return nil
+}
+
+// loadMemory 把文件的内容写入内存
+func (ui *UI) loadMemory(args []string) error {
+	if len(args) != 2 {
+		return ui.viewHelp([]string{"load-memory"})
+	}
+	address, err := EvaluateAddress(args[1])
+	if err != nil {
+		return err
+	}
+	n, err := loadMemory(args[0], address)
+	if err != nil {
+		return err
+	}
+	err = ui.flashData()
+	if err != nil {
+		return err
+	}
+	ui.StepInfoView(fmt.Sprintf("已将 %s 的 0x%x 个字节写入 0x%x", args[0], n, address))
+	return nil
+}
+
+// viewAs 把某地址的内存当作 Go 类型显示，例如 view 0xc0000a2000 as *net/http.Request
+func (ui *UI) viewAs(args []string) error {
+	if len(args) != 3 || args[1] != "as" {
+		return ui.viewHelp([]string{"view"})
+	}
+	address, err := EvaluateAddress(args[0])
+	if err != nil {
+		return err
+	}
+	if view, ok := ui.views["third"]; ok {
+		return view.ViewAs(address, args[2])
+	}
+	return nil
+}
+
+// layout 显示 Go 类型的内存布局，并给出填充最少的字段顺序
+func (ui *UI) layout(args []string) error {
+	if len(args) != 1 {
+		return ui.viewHelp([]string{"layout"})
+	}
+	if view, ok := ui.views["third"]; ok {
+		return view.Layout(args[0])
+	}
+	return nil
+}
+
+// disassembly 查看从某地址开始的汇编代码，或者某个函数完整的汇编代码
 func (ui *UI) disassembly(args []string) error {
 	if args == nil || len(args) == 0 {
 		return ui.viewHelp([]string{"d"})
@@ -210,17 +457,19 @@ func (ui *UI) disassembly(args []string) error {
 		// todo: return help error
 		return nil
 	}
-	addr, err := utils.StringToUint64(args[0])
+	view, ok := ui.views["first"]
+	if !ok {
+		return nil
+	}
+	address, isSymbol, err := parseLocation(args[0])
 	if err != nil {
 		return err
 	}
-	if view, ok := ui.views["first"]; ok {
-		err = view.DisassemblyAddress(addr)
-		if err != nil {
-			return err
-		}
+	if isSymbol {
+		// 不是地址的话，当作函数名处理
+		return view.DisassemblyFunction(args[0])
 	}
-	return nil
+	return view.DisassemblyAddress(address)
 }
 
 // listBreakpoints 列出当前所有的断点
@@ -229,12 +478,60 @@ func (ui *UI) listBreakpoints(args []string) error {
 	return ui.flashData()
 }
 
+// frame 选择某一帧，并在左下角显示该栈帧的布局
+func (ui *UI) frame(args []string) error {
+	if len(args) > 1 {
+		return ui.viewHelp([]string{"frame"})
+	}
+	if len(args) == 1 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil {
+			return err
+		}
+		err = client.SelectFrame(n)
+		if err != nil {
+			return err
+		}
+	}
+	ui.FrameLayoutView()
+	return ui.flashData()
+}
+
+// memory 在左下角显示从 Rsp 开始的栈上的内存
+func (ui *UI) memory(args []string) error {
+	ui.MemoryView()
+	return ui.flashData()
+}
+
 // viewStacktrace 查看当前的调用栈
 func (ui *UI) viewStacktrace(args []string) error {
 	ui.StackView()
 	return ui.flashData()
 }
 
+// viewThreads 查看所有的线程
+func (ui *UI) viewThreads(args []string) error {
+	ui.ThreadsView()
+	return ui.flashData()
+}
+
+// thread 切换到某个线程，寄存器和反汇编显示该线程的状态
+func (ui *UI) thread(args []string) error {
+	if len(args) != 1 {
+		return ui.viewHelp([]string{"thread"})
+	}
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return err
+	}
+	err = client.SwitchThread(id)
+	if err != nil {
+		return err
+	}
+	ui.ThreadsView()
+	return ui.flashData()
+}
+
 // viewHistory 查看历史命令记录
 func (ui *UI) viewHistory(args []string) error {
 	ui.HistoryView()
@@ -283,7 +580,7 @@ func (ui *UI) print(args []string) error {
 	if len(args) != 2 {
 		return nil
 	}
-	address, err := utils.StringToUint64(args[0])
+	address, err := EvaluateAddress(args[0])
 	if err != nil {
 		return err
 	}
@@ -300,66 +597,144 @@ func (ui *UI) print(args []string) error {
 	return nil
 }
 
+// monitor 添加监视器，保存的是表达式，每次停下时重新计算地址
 func (ui *UI) monitor(args []string) error {
 	if args == nil || len(args) == 0 {
 		return ui.viewMonitors()
 	}
-	var size int
-	var address string
-	var err error
-	// todo: 支持寄存器和运算
-	address, err = CalculateAddress(args[0])
+	switch args[0] {
+	case "history":
+		return ui.monitorHistory(args[1:])
+	case "interval":
+		return ui.monitorIntervalCommand(args[1:])
+	case "list":
+		ui.MonitorListView(monitors.getMonitorsList())
+		return nil
+	case "clear":
+		monitors.clear()
+		return ui.viewMonitors()
+	case "delete", "enable", "disable":
+		return ui.editMonitors(args[0], args[1:])
+	case "rename":
+		if len(args) != 3 {
+			return ui.viewHelp([]string{"m"})
+		}
+		if err := monitors.rename(args[1], args[2]); err != nil {
+			return err
+		}
+		return ui.viewMonitors()
+	}
+	isBreakpoint, condition, args, err := ParseBreakFlags(args)
+	if err != nil {
+		return err
+	}
+	binding, rest, err := ParseBinding(args, client.Current.Function)
 	if err != nil {
 		return err
 	}
+	display := DisplayType{Name: "hex", Size: 4}
+	switch len(rest) {
+	case 0:
+	case 1:
+		display, err = ParseDisplayType(rest[0])
+		if err != nil {
+			return err
+		}
+	default:
+		return ui.viewHelp([]string{"m"})
+	}
+	monitors.add(binding, display, isBreakpoint, condition)
+	// 立刻读取一次，之后的变化才会让程序停下
+	monitors.monitorAddress()
+	return ui.viewMonitors()
+}
+
+// editMonitors 删除、启用或停用一个或多个监视器
+func (ui *UI) editMonitors(action string, refs []string) error {
+	if len(refs) == 0 {
+		return ui.viewHelp([]string{"m"})
+	}
+	for _, ref := range refs {
+		var err error
+		switch action {
+		case "delete":
+			err = monitors.remove(ref)
+		case "enable":
+			err = monitors.setEnabled(ref, true)
+		case "disable":
+			err = monitors.setEnabled(ref, false)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return ui.viewMonitors()
+}
 
+// monitorIntervalCommand 设置 c & 时刷新监视器的间隔，单位是毫秒，没有参数时显示当前的间隔
+func (ui *UI) monitorIntervalCommand(args []string) error {
 	switch len(args) {
+	case 0:
 	case 1:
-		size = 4
-	case 2:
-		size, err = strconv.Atoi(args[1])
+		ms, err := strconv.Atoi(args[0])
 		if err != nil {
 			return err
 		}
+		if ms < 0 {
+			return fmt.Errorf("invalid interval %d", ms)
+		}
+		monitorInterval = time.Duration(ms) * time.Millisecond
 	default:
 		return ui.viewHelp([]string{"m"})
 	}
-	monitors.add(address, size)
-	return ui.viewMonitors()
+	if monitorInterval == 0 {
+		ui.StepInfoView("c & 时不刷新监视器，只在程序停下时更新")
+		return nil
+	}
+	ui.StepInfoView(fmt.Sprintf("c & 时每 %v 刷新监视器", monitorInterval))
+	return nil
+}
+
+// monitorHistory 显示监视器的值每次变化的记录
+func (ui *UI) monitorHistory(args []string) error {
+	if len(args) != 1 {
+		return ui.viewHelp([]string{"m"})
+	}
+	data, err := monitors.historyData(args[0])
+	if err != nil {
+		return err
+	}
+	ui.MonitorHistoryView(data)
+	return nil
 }
 
 func (ui *UI) track(args []string) error {
-	// track action <address> [size]
+	// track action <expression> [size]
 	if args == nil || len(args) == 0 {
 		return ui.viewTrackers()
 	}
 
 	switch args[0] {
-	// track add <address> [size=4]
+	// track add <expression> [size=4] [--global | --in <function>]
 	case "add":
-		address, err := CalculateAddress(args[1])
+		binding, rest, err := ParseBinding(args[1:], client.Current.Function)
 		if err != nil {
 			return err
 		}
-		var size = 4
-		if len(args) == 3 {
-			size, err = strconv.Atoi(args[2])
+		display := DisplayType{Name: "hex", Size: 4}
+		if len(rest) == 1 {
+			display, err = ParseDisplayType(rest[0])
 			if err != nil {
 				return err
 			}
 		}
-		trackers.add(address, size)
-	// track delete <address>
+		trackers.add(binding, display)
+	// track delete <expression>
 	case "delete":
-		address, err := CalculateAddress(args[1])
-		if err != nil {
-			return err
-		}
 		if len(args) == 2 {
-			trackers.remove(address)
+			trackers.remove(args[1])
 		} else {
-			// todo: view help error
-			return nil
+			return ui.viewHelp([]string{"track"})
 		}
 
 	case "continue":
@@ -26,6 +26,22 @@ func (ui *UI) StackView() {
 	}
 }
 
+// ReturnValuesView 是在右下角显示 step-out 之后被调函数的返回值
+func (ui *UI) ReturnValuesView() {
+	if view, ok := ui.views["fourth"]; ok {
+		view.handle = view.ReturnValues
+		view.title = "返回值"
+	}
+}
+
+// ThreadsView 是在右下角显示所有的线程
+func (ui *UI) ThreadsView() {
+	if view, ok := ui.views["fourth"]; ok {
+		view.handle = view.ThreadsInfo
+		view.title = "线程"
+	}
+}
+
 // HistoryView 是在右下角显示历史命令记录
 func (ui *UI) HistoryView() {
 	if view, ok := ui.views["fourth"]; ok {
@@ -50,6 +66,14 @@ func (ui *UI) RegistersView() {
 	}
 }
 
+// VectorRegistersView 是在右上角显示向量寄存器
+func (ui *UI) VectorRegistersView() {
+	if view, ok := ui.views["second"]; ok {
+		view.handle = view.VectorRegisters
+		view.title = "向量寄存器"
+	}
+}
+
 // MemoryView 是在左下角显示内存信息，默认起始地址为 Rsp 寄存器的值
 func (ui *UI) MemoryView() {
 	if view, ok := ui.views["third"]; ok {
@@ -58,6 +82,14 @@ func (ui *UI) MemoryView() {
 	}
 }
 
+// FrameLayoutView 是在左下角显示当前选择的栈帧的布局
+func (ui *UI) FrameLayoutView() {
+	if view, ok := ui.views["third"]; ok {
+		view.handle = view.FrameLayout
+		view.title = fmt.Sprintf("栈帧 %d", client.Current.Statement)
+	}
+}
+
 // DisassemblyView 是在左上角显示反汇编内容的指针，默认起始地址为 Rip 寄存器的值
 func (ui *UI) DisassemblyView() {
 	if view, ok := ui.views["first"]; ok {
@@ -74,6 +106,25 @@ func (ui *UI) HelpView(info string) {
 	}
 }
 
+// StepInfoView 是在右下角显示单步执行等命令的结果
+func (ui *UI) StepInfoView(info string) {
+	if view, ok := ui.views["fourth"]; ok {
+		view.data = []string{info}
+		view.title = "执行信息"
+	}
+}
+
+// SkipListView 是在右下角显示 step 时跳过的包和文件
+func (ui *UI) SkipListView() {
+	if view, ok := ui.views["fourth"]; ok {
+		view.data = client.SkipPatterns()
+		if len(view.data) == 0 {
+			view.data = []string{"跳过列表为空"}
+		}
+		view.title = "跳过列表"
+	}
+}
+
 // focusTo 聚焦到某个 Item
 func (ui *UI) focusTo(name string) error {
 	ui.grid = tview.NewGrid().
@@ -145,6 +196,22 @@ func (ui *UI) MonitorView() error {
 	return nil
 }
 
+// MonitorHistoryView 是在右下角显示监视器的历史记录
+func (ui *UI) MonitorHistoryView(data []string) {
+	if view, ok := ui.views["fourth"]; ok {
+		view.data = data
+		view.title = "监视器历史"
+	}
+}
+
+// MonitorListView 是在右下角显示所有监视器的详细信息
+func (ui *UI) MonitorListView(data []string) {
+	if view, ok := ui.views["fourth"]; ok {
+		view.data = data
+		view.title = "监视器列表"
+	}
+}
+
 // MonitorView2 另一种修改 View 的方式，和 ErrorView 一样，直接用 view.updateView 来更改内容
 // 当监控的数据发生变化的时候，才会调用
 func (ui *UI) MonitorView2() {
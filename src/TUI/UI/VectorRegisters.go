@@ -0,0 +1,150 @@
+package UI
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"github.com/go-delve/delve/service/api"
+	"math"
+	"strings"
+)
+
+// VectorFormats 是向量寄存器支持的 lane 格式
+var VectorFormats = []string{"hex", "i8", "i16", "i32", "i64", "f32", "f64"}
+
+// vectorFormats 保存每个向量寄存器的 lane 格式，key 是大写的 XMM 寄存器名，没有设置的为 hex
+var vectorFormats = make(map[string]string)
+
+// isVectorFormat 判断是不是支持的 lane 格式
+func isVectorFormat(format string) bool {
+	for _, f := range VectorFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// vectorRegisterName 把 xmm3、ymm3、zmm3 统一成 delve 使用的 XMM3
+func vectorRegisterName(name string) string {
+	name = strings.ToUpper(name)
+	if strings.HasPrefix(name, "YMM") || strings.HasPrefix(name, "ZMM") {
+		name = "XMM" + name[3:]
+	}
+	return name
+}
+
+// setVectorFormat 设置向量寄存器的 lane 格式，name 为 all 时设置所有的寄存器
+func setVectorFormat(name, format string) error {
+	if !isVectorFormat(format) {
+		return fmt.Errorf("unknown vector format %s, available: %s", format, strings.Join(VectorFormats, "/"))
+	}
+	if name == "all" {
+		vectorFormats = make(map[string]string)
+		if format != "hex" {
+			for i := 0; i < 32; i++ {
+				vectorFormats[fmt.Sprintf("XMM%d", i)] = format
+			}
+		}
+		return nil
+	}
+	name = vectorRegisterName(name)
+	if !isVectorRegister(strings.ToLower(name)) {
+		return fmt.Errorf("%s is not a vector register", name)
+	}
+	vectorFormats[name] = format
+	return nil
+}
+
+// ParseVectorRegister 从 delve 格式化后的 XMM 寄存器的值中解析出原始字节（小端序）
+// delve 先输出低 128 位，有 AVX 时再输出 [YMMnh] 高 128 位，AVX-512 时还有 ZMM 的两部分
+func ParseVectorRegister(value string) []byte {
+	result := make([]byte, 0, 64)
+	for _, field := range strings.Fields(value) {
+		if !strings.HasPrefix(field, "0x") || len(field) != 2+32 {
+			continue
+		}
+		data, err := hex.DecodeString(field[2:])
+		if err != nil {
+			continue
+		}
+		// 显示的是大端序，倒过来
+		for i := len(data) - 1; i >= 0; i-- {
+			result = append(result, data[i])
+		}
+	}
+	return result
+}
+
+// FormatVectorLanes 按照 format 把向量寄存器的字节格式化成 lane，lane 0 在最前面
+func FormatVectorLanes(data []byte, format string) string {
+	lanes := make([]string, 0)
+	switch format {
+	case "i8":
+		for _, b := range data {
+			lanes = append(lanes, fmt.Sprintf("%d", int8(b)))
+		}
+	case "i16":
+		for i := 0; i+2 <= len(data); i += 2 {
+			lanes = append(lanes, fmt.Sprintf("%d", int16(binary.LittleEndian.Uint16(data[i:]))))
+		}
+	case "i32":
+		for i := 0; i+4 <= len(data); i += 4 {
+			lanes = append(lanes, fmt.Sprintf("%d", int32(binary.LittleEndian.Uint32(data[i:]))))
+		}
+	case "i64":
+		for i := 0; i+8 <= len(data); i += 8 {
+			lanes = append(lanes, fmt.Sprintf("%d", int64(binary.LittleEndian.Uint64(data[i:]))))
+		}
+	case "f32":
+		for i := 0; i+4 <= len(data); i += 4 {
+			lanes = append(lanes, fmt.Sprintf("%g", math.Float32frombits(binary.LittleEndian.Uint32(data[i:]))))
+		}
+	case "f64":
+		for i := 0; i+8 <= len(data); i += 8 {
+			lanes = append(lanes, fmt.Sprintf("%g", math.Float64frombits(binary.LittleEndian.Uint64(data[i:]))))
+		}
+	default:
+		sb := strings.Builder{}
+		sb.WriteString("0x")
+		for i := len(data) - 1; i >= 0; i-- {
+			sb.WriteString(fmt.Sprintf("%02x", data[i]))
+		}
+		return sb.String()
+	}
+	return "{" + strings.Join(lanes, " ") + "}"
+}
+
+// VectorRegsToStrings 格式化所有的向量寄存器，有 AVX 时显示为 YMM，changed 中的寄存器标红
+func VectorRegsToStrings(regs api.Registers, changed map[string]bool) []string {
+	result := make([]string, 0)
+	for _, reg := range regs {
+		if !isVectorRegister(strings.ToLower(reg.Name)) {
+			continue
+		}
+		data := ParseVectorRegister(reg.Value)
+		if len(data) == 0 {
+			continue
+		}
+		name := reg.Name
+		switch len(data) {
+		case 32:
+			name = "Y" + name[1:]
+		case 64:
+			name = "Z" + name[1:]
+		}
+		format, ok := vectorFormats[reg.Name]
+		if !ok {
+			format = "hex"
+		}
+		line := fmt.Sprintf("%-5s %-3s %s", name, format, FormatVectorLanes(data, format))
+		if changed[reg.Name] {
+			line = fmt.Sprintf("[red]%s[white]", line)
+		}
+		result = append(result, line)
+	}
+	if len(result) == 0 {
+		result = append(result, "没有获取到向量寄存器")
+	}
+	return result
+}
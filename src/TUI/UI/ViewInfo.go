@@ -15,14 +15,58 @@ type viewInfo struct {
 	view     *tview.TextView
 	data     []string
 	row, col int
+	// cursor 是聚焦时光标所在的行，-1 表示没有光标
+	cursor int
 }
 
-// setTextView 设置 view 的内容和标题
+// setTextView 设置 view 的内容和标题，聚焦时反色显示光标所在的行
 func (info *viewInfo) setTextView() error {
-	info.updateView(info.title, strings.Join(info.data, "\n"))
+	data := info.data
+	if info.cursor >= 0 && info.cursor < len(data) && info.view.HasFocus() {
+		data = make([]string, len(info.data))
+		copy(data, info.data)
+		data[info.cursor] = "[::r]" + data[info.cursor] + "[::-]"
+	}
+	info.updateView(info.title, strings.Join(data, "\n"))
 	return nil
 }
 
+// moveCursor 把光标移动到上一条或下一条指令处，并让光标保持在可见范围内
+func (info *viewInfo) moveCursor(step int) {
+	if info.cursor < 0 || info.cursor >= len(info.data) {
+		// 第一次移动时从 Rip 所在的行开始
+		info.cursor = 0
+		for i, line := range info.data {
+			if strings.HasPrefix(line, "[red]0x") {
+				info.cursor = i
+				break
+			}
+		}
+	} else {
+		for i := info.cursor + step; i >= 0 && i < len(info.data); i += step {
+			if _, ok := lineAddress(info.data[i]); ok {
+				info.cursor = i
+				break
+			}
+		}
+	}
+	row, _ := info.view.GetScrollOffset()
+	_, _, _, height := info.view.GetInnerRect()
+	if info.cursor < row {
+		info.view.ScrollTo(info.cursor, 0)
+	} else if height > 0 && info.cursor >= row+height {
+		info.view.ScrollTo(info.cursor-height+1, 0)
+	}
+}
+
+// cursorAddress 得到光标所在指令的地址
+func (info *viewInfo) cursorAddress() (uint64, bool) {
+	if info.cursor < 0 || info.cursor >= len(info.data) {
+		return 0, false
+	}
+	return lineAddress(info.data[info.cursor])
+}
+
 // updateView 更新 view 的信息，包括内容和标题
 func (info *viewInfo) updateView(title string, data string) {
 	info.view.Clear()
@@ -40,39 +84,116 @@ func (info *viewInfo) Disassembly() error {
 	return nil
 }
 
+// Registers 按分组显示所有的寄存器，值发生变化的寄存器标红
 func (info *viewInfo) Registers() error {
 	regs, err := client.ListRegs()
 	if err != nil {
 		return err
 	}
-	info.data = RegsToStrings(regs)
-	// 对发生变化的寄存器标红
-	oldRegs := strings.Split(info.view.GetText(false), "\n")
-	if len(oldRegs) > 1 {
-		for i := 1; i < 17; i++ {
-			oldRegs[i] = strings.Replace(oldRegs[i], "[red]", "", -1)
-			oldRegs[i] = strings.Replace(oldRegs[i], "[white]", "", -1)
-			if oldRegs[i] != info.data[i] {
-				info.data[i] = fmt.Sprintf("[red]%s[white]", info.data[i])
+	info.data = RegsToStrings(regs, changedRegisters(regs))
+	return nil
+}
+
+// VectorRegisters 显示 SSE/AVX 向量寄存器，每个寄存器按照设置的 lane 格式显示
+func (info *viewInfo) VectorRegisters() error {
+	regs, err := client.ListRegs()
+	if err != nil {
+		return err
+	}
+	info.data = VectorRegsToStrings(regs, changedRegisters(regs))
+	return nil
+}
+
+// ExamineMemory 按照 spec 显示从 start 开始的内存，s 格式显示字符串，i 格式显示指令
+func (info *viewInfo) ExamineMemory(start uint64, spec ExamineSpec) error {
+	switch spec.Format {
+	case 's':
+		return info.examineStrings(start, spec.Count)
+	case 'i':
+		return info.examineInstructions(start, spec.Count)
+	}
+	length := spec.Bytes()
+	if length > maxExamineBytes {
+		return fmt.Errorf("too many bytes to examine: 0x%x, at most 0x%x", length, maxExamineBytes)
+	}
+	mems, err := client.ReadMemory(start, int(length))
+	if err != nil {
+		return err
+	}
+	info.data = FormatMemory(mems, start, spec)
+	return nil
+}
+
+// examineStrings 显示从 start 开始的 count 个字符串，一段一段地读取，直到读到足够的字符串
+func (info *viewInfo) examineStrings(start uint64, count int) error {
+	if count == 0 {
+		count = 1
+	}
+	data := make([]byte, 0)
+	for countStrings(data) < count && len(data) < maxExamineBytes {
+		mems, err := client.ExamineMemory(start+uint64(len(data)), 0x40)
+		if err != nil {
+			if len(data) == 0 {
+				return err
 			}
+			// 读到了没有映射的地址
+			break
 		}
+		data = append(data, mems...)
 	}
+	info.data = FormatStrings(data, start, count)
 	return nil
 }
 
-func (info *viewInfo) ExamineMemory(start, mode uint64, format string) error {
-	ends := start + 0x80
-	mems, err := client.ExamineMemory(start, int(ends-start))
+// examineInstructions 显示从 start 开始的 count 条指令
+func (info *viewInfo) examineInstructions(start uint64, count int) error {
+	if count == 0 {
+		count = defaultExamineInstructions
+	}
+	// x86 的指令最长 15 个字节
+	ends := start + uint64(count)*15
+	if ends-start > maxExamineBytes {
+		return fmt.Errorf("too many instructions to examine: %d", count)
+	}
+	asms, err := client.Disassembly2(start, ends)
 	if err != nil {
 		return err
 	}
-	info.data = FormatMemory(mems, start, mode, format)
+	if len(asms) > count {
+		asms = asms[:count]
+	}
+	result := make([]string, 0, len(asms)+1)
+	preFunc := ""
+	for _, asm := range asms {
+		if functionName := asm.Loc.Function.Name(); functionName != preFunc {
+			result = append(result, formatFunctionLine(functionName))
+			preFunc = functionName
+		}
+		result = append(result, formatASMLine(asm, client.Current.Rip))
+	}
+	info.data = result
 	return nil
 }
 
+// ExamineStack 显示从 Rsp 开始的栈上的数据，并显示每个值指向的内容
 func (info *viewInfo) ExamineStack() error {
 	start := client.Current.Rsp
-	return info.ExamineMemory(start, 8, "hex")
+	mems, err := client.ExamineMemory(start, 0x80)
+	if err != nil {
+		return err
+	}
+	info.data = FormatStack(mems, start)
+	return nil
+}
+
+// FrameLayout 显示当前选择的栈帧的布局，标注返回地址、保存的 rbp、局部变量和参数
+func (info *viewInfo) FrameLayout() error {
+	mems, start, labels, boundaries, err := frameLayout(client.Current.Statement)
+	if err != nil {
+		return err
+	}
+	info.data = FormatFrameLayout(mems, start, labels, boundaries)
+	return nil
 }
 
 func (info *viewInfo) StackInfo() error {
@@ -81,6 +202,30 @@ func (info *viewInfo) StackInfo() error {
 	return nil
 }
 
+// ReturnValues 显示 step-out 之后的返回值，执行下一条命令之后返回值被清空，恢复显示调用栈
+func (info *viewInfo) ReturnValues() error {
+	if len(client.Current.ReturnValues) == 0 {
+		info.handle = info.StackInfo
+		info.title = "调用栈"
+		return info.StackInfo()
+	}
+	info.data = make([]string, 0, len(client.Current.ReturnValues))
+	for _, variable := range client.Current.ReturnValues {
+		info.data = append(info.data, fmt.Sprintf("%s %s = %s", tview.Escape(variable.Name), tview.Escape(variable.Type), variableValue(variable)))
+	}
+	return nil
+}
+
+// ThreadsInfo 显示所有的线程，以及线程的 PC、所在函数和正在运行的协程
+func (info *viewInfo) ThreadsInfo() error {
+	threads, err := client.ListThreads()
+	if err != nil {
+		return err
+	}
+	info.data = ThreadsToStrings(threads, client.Current.ThreadID)
+	return nil
+}
+
 func (info *viewInfo) HistoryInfo() error {
 	info.data = history
 	return nil
@@ -105,6 +250,16 @@ func (info *viewInfo) DisassemblyAddress(addr uint64) error {
 	return nil
 }
 
+// DisassemblyFunction 反汇编整个函数，数据不做截断，可以在聚焦后上下翻看
+func (info *viewInfo) DisassemblyFunction(functionName string) error {
+	asms, bodyPC, err := client.DisassemblyFunction(functionName)
+	if err != nil {
+		return err
+	}
+	info.data = FormatFunctionASM(asms, client.Current.Rip, bodyPC)
+	return nil
+}
+
 func (info *viewInfo) PrintAddress(addr uint64, size int) error {
 	data, err := client.GetDataFromAddress(addr, size)
 	if err != nil {
@@ -134,5 +289,6 @@ func NewTextViewInfo(title string, row, col int, handler func(), doneFunc func(k
 	info.view.SetDoneFunc(doneFunc)
 	info.row = row
 	info.col = col
+	info.cursor = -1
 	return info
 }
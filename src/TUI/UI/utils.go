@@ -2,8 +2,8 @@ package UI
 
 import (
 	"fmt"
-	"github.com/Knetic/govaluate"
 	"github.com/go-delve/delve/service/api"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -13,7 +13,6 @@ var wordList []string
 func FormatASM(asms api.AsmInstructions, ip uint64) []string {
 	result := make([]string, 0, 0)
 	preFunc := ""
-	funcLine := ""
 
 	// 显示至多 17 行汇编代码
 	lines := 17
@@ -22,134 +21,178 @@ func FormatASM(asms api.AsmInstructions, ip uint64) []string {
 	}
 
 	for i := 0; i < lines; i++ {
-		line := ""
 		functionName := asms[i].Loc.Function.Name()
 		if functionName != preFunc {
-			if functionName != "" {
-				funcLine = fmt.Sprintf("[yellow]; Function %s [white]", functionName)
-			} else {
-				funcLine = "[yellow];  [white]"
-			}
-			result = append(result, funcLine)
+			result = append(result, formatFunctionLine(functionName))
 			preFunc = functionName
 		}
-		pc := asms[i].Loc.PC
-		line = fmt.Sprintf("0x%x    [p]    %s", pc, asms[i].Text)
-		if asms[i].Breakpoint {
-			line = strings.Replace(line, "[p]", "#", 1)
-		} else {
-			line = strings.Replace(line, "[p]", " ", 1)
+		result = append(result, formatASMLine(asms[i], ip))
+	}
+	return result
+}
+
+// FormatFunctionASM 格式化整个函数的汇编代码，不限制行数，并标注序言、函数体、尾声和栈扩容部分
+// bodyPC 是序言结束的地址
+func FormatFunctionASM(asms api.AsmInstructions, ip, bodyPC uint64) []string {
+	result := make([]string, 0, len(asms)+8)
+	if len(asms) == 0 {
+		return result
+	}
+	result = append(result, formatFunctionLine(asms[0].Loc.Function.Name()))
+
+	markers := make(map[int]string)
+	if asms[0].Loc.PC != bodyPC {
+		markers[0] = "prologue"
+	}
+	var moreStackPC uint64
+	for i, asm := range asms {
+		if asm.Loc.PC == bodyPC {
+			markers[i] = "body"
+		}
+		// 序言中栈检查失败时跳转的目标就是调用 runtime.morestack 的部分
+		if asm.Loc.PC < bodyPC && moreStackPC == 0 && isJumpInstruction(asm.Text) {
+			moreStackPC = jumpTarget(asm.Text)
 		}
+		if isRetInstruction(asm.Text) {
+			// 往前找到恢复栈帧的指令，作为尾声的开始
+			start := i
+			for start > 0 && isEpilogueInstruction(asms[start-1].Text) {
+				start--
+			}
+			markers[start] = "epilogue"
+			if i+1 < len(asms) {
+				if _, ok := markers[i+1]; !ok {
+					markers[i+1] = "body"
+				}
+			}
+		}
+	}
+	for i, asm := range asms {
+		if moreStackPC != 0 && asm.Loc.PC == moreStackPC {
+			markers[i] = "morestack"
+		}
+	}
 
-		if pc == ip {
-			line = "[red]" + line + "[white]"
+	for i, asm := range asms {
+		if marker, ok := markers[i]; ok {
+			result = append(result, fmt.Sprintf("[yellow]; ---- %s ----[white]", marker))
 		}
-		result = append(result, line)
+		result = append(result, formatASMLine(asm, ip))
 	}
 	return result
 }
 
-func RegsToStrings(regs api.Registers) []string {
-	result := make([]string, 0, 0)
-	for i := 0; i <= 16; i++ {
-		line := fmt.Sprintf("%-3s     %s", regs[i].Name, regs[i].Value)
-		result = append(result, line)
+// formatFunctionLine 生成函数名的注释行
+func formatFunctionLine(functionName string) string {
+	if functionName != "" {
+		return fmt.Sprintf("[yellow]; Function %s [white]", functionName)
 	}
-	return result
+	return "[yellow];  [white]"
 }
 
-func StacktraceToStrings(stacktrace []api.Stackframe) []string {
-	result := make([]string, 0, 0)
-	for _, stack := range stacktrace {
-		line := fmt.Sprintf("%s:%d", stack.Function.Name(), stack.Line)
-		result = append(result, line)
+// formatASMLine 格式化一条汇编指令，有断点的用 # 标注，当前 Rip 所指的指令标红并加上注释
+func formatASMLine(asm api.AsmInstruction, ip uint64) string {
+	pc := asm.Loc.PC
+	line := fmt.Sprintf("0x%x    [p]    %s", pc, asm.Text)
+	if asm.Breakpoint {
+		line = strings.Replace(line, "[p]", "#", 1)
+	} else {
+		line = strings.Replace(line, "[p]", " ", 1)
 	}
 
-	return result
+	if pc == ip {
+		line = "[red]" + line + "[white]"
+		if annotation := annotateInstruction(asm); annotation != "" {
+			line += "    [yellow]" + annotation + "[white]"
+		}
+	}
+	return line
 }
 
-func BreakpointsToStrings(breakpoints []*api.Breakpoint) []string {
-	result := make([]string, 0, 0)
-	for _, point := range breakpoints {
-		if point.ID < 0 {
-			continue
-		}
-		line := fmt.Sprintf("%02d | %s:%d", point.ID, point.FunctionName, point.Line)
-		result = append(result, line)
+// lineAddress 从格式化后的汇编代码行中解析出指令地址，注释行返回 false
+func lineAddress(line string) (uint64, bool) {
+	line = strings.TrimPrefix(line, "[red]")
+	if !strings.HasPrefix(line, "0x") {
+		return 0, false
 	}
-	return result
+	fields := strings.Fields(line)
+	address, err := strconv.ParseUint(fields[0], 0, 64)
+	if err != nil {
+		return 0, false
+	}
+	return address, true
+}
+
+// isRetInstruction 判断是不是 ret 指令
+func isRetInstruction(text string) bool {
+	return text == "ret" || strings.HasPrefix(text, "ret ")
 }
 
-func CountInLineWithMode(mode uint64) uint64 {
-	switch mode {
-	case 1:
-		return 8
-	case 2:
-		return 4
-	case 4:
-		return 4
-	case 8:
-		return 2
-	default:
+// isEpilogueInstruction 判断是不是函数尾声中恢复栈帧的指令
+func isEpilogueInstruction(text string) bool {
+	return strings.HasPrefix(text, "add rsp, ") || text == "pop rbp" || text == "leave"
+}
+
+// isJumpInstruction 判断是不是跳转指令（包括条件跳转）
+func isJumpInstruction(text string) bool {
+	return len(text) > 1 && text[0] == 'j'
+}
+
+// jumpTarget 从跳转指令中解析出目标地址，解析不了的返回 0
+func jumpTarget(text string) uint64 {
+	fields := strings.Fields(text)
+	if len(fields) != 2 {
 		return 0
 	}
+	target, err := strconv.ParseUint(fields[1], 0, 64)
+	if err != nil {
+		return 0
+	}
+	return target
 }
 
-func getFormat(signal byte) string {
-	format := ""
-	switch signal {
-	case 'x':
-		format = "hex"
-	case 'd':
-		format = "dec"
+func StacktraceToStrings(stacktrace []api.Stackframe) []string {
+	result := make([]string, 0, 0)
+	for _, stack := range stacktrace {
+		line := fmt.Sprintf("%s:%d", stack.Function.Name(), stack.Line)
+		result = append(result, line)
 	}
-	return format
+
+	return result
 }
 
-func getMode(signal byte) uint64 {
-	var mode uint64 = 0
-	switch signal {
-	case 'g':
-		mode = 8
-	case 'w':
-		mode = 4
-	case 'h':
-		mode = 2
-	case 'b':
-		mode = 1
+// ThreadsToStrings 格式化线程列表，当前线程用 * 标注
+func ThreadsToStrings(threads []*api.Thread, current int) []string {
+	result := make([]string, 0, len(threads))
+	sort.Slice(threads, func(i, j int) bool {
+		return threads[i].ID < threads[j].ID
+	})
+	for _, thread := range threads {
+		mark := " "
+		if thread.ID == current {
+			mark = "*"
+		}
+		goroutine := "-"
+		if thread.GoroutineID > 0 {
+			goroutine = fmt.Sprintf("%d", thread.GoroutineID)
+		}
+		line := fmt.Sprintf("%s%-6d 0x%x  g:%-4s %s", mark, thread.ID, thread.PC, goroutine, thread.Function.Name())
+		if thread.ID == current {
+			line = fmt.Sprintf("[red]%s[white]", line)
+		}
+		result = append(result, line)
 	}
-	return mode
+	return result
 }
 
-// FormatMemory 格式化数据，例如 x gx addr 会变成每行 2 个 8 字节的数据
-func FormatMemory(mems []byte, start uint64, mode uint64, format string) []string {
+func BreakpointsToStrings(breakpoints []*api.Breakpoint) []string {
 	result := make([]string, 0, 0)
-	var offset uint64
-	for len(mems) > 0 {
-		sb := strings.Builder{}
-		address := fmt.Sprintf("0x%x", start+offset)
-		sb.WriteString(address)
-		sb.WriteString("    ")
-		count := CountInLineWithMode(mode)
-		var j uint64
-		for j = 0; j < count; j++ {
-			sb.WriteString("0x")
-			var i uint64
-			for i = 0; i < mode; i++ {
-				index := (j+1)*mode - i - 1
-				if format == "hex" {
-					sb.WriteString(fmt.Sprintf("%02x", mems[index]))
-				}
-				// todo: make dec format
-				//else if format == "dec" {
-				//	sb.WriteString(fmt.Sprintf("%03d", mems[index]))
-				//}
-			}
-			sb.WriteString(" ")
+	for _, point := range breakpoints {
+		if point.ID < 0 {
+			continue
 		}
-		mems = mems[count*mode:]
-		offset += count * mode
-		result = append(result, sb.String())
+		line := fmt.Sprintf("%02d | %s:%d", point.ID, point.FunctionName, point.Line)
+		result = append(result, line)
 	}
 	return result
 }
@@ -181,79 +224,3 @@ func AutoComplete(current string) []string {
 	}
 	return result
 }
-
-func lastIndexOfNumberOrLetter(arg string) int {
-	for i := 0; i < len(arg); i++ {
-		if (arg[i] <= 'z' && arg[i] >= 'a') || (arg[i] >= '0' && arg[i] <= '9') {
-			continue
-		} else {
-			return i
-		}
-	}
-	return -1
-}
-
-func ParseArgs(arg string) (string, error) {
-	unChangedString := strings.ToLower(arg)
-	var result strings.Builder
-	for len(unChangedString) > 0 {
-		if unChangedString[0] == '$' {
-			var regName string
-			lastIndex := lastIndexOfNumberOrLetter(unChangedString[1:])
-			if lastIndex == -1 {
-				regName = unChangedString[1:]
-				unChangedString = ""
-			} else {
-				regName = unChangedString[1 : 1+lastIndex]
-				unChangedString = unChangedString[1+lastIndex:]
-			}
-			for _, reg := range client.Current.Regs {
-				if strings.ToLower(reg.Name) == regName {
-					tmp, err := strconv.ParseInt(reg.Value, 0, 64)
-					if err != nil {
-						return "", err
-					}
-					result.WriteString(fmt.Sprintf("%d", tmp))
-					break
-				}
-			}
-		} else if len(unChangedString) > 1 && unChangedString[0] == '0' && unChangedString[1] == 'x' {
-			lastIndex := lastIndexOfNumberOrLetter(unChangedString[2:])
-			var hex string
-			if lastIndex == -1 {
-				hex = unChangedString
-				unChangedString = ""
-			} else {
-				hex = unChangedString[:lastIndex+2]
-				unChangedString = unChangedString[lastIndex+2:]
-			}
-			value, err := strconv.ParseInt(hex, 0, 64)
-			if err != nil {
-				return "", err
-			}
-			result.WriteString(fmt.Sprintf("%d", value))
-		} else {
-			result.WriteByte(unChangedString[0])
-			unChangedString = unChangedString[1:]
-		}
-
-	}
-	return result.String(), nil
-}
-
-func CalculateAddress(expr string) (string, error) {
-	e, err := ParseArgs(expr)
-	if err != nil {
-		return "", err
-	}
-	expression, err := govaluate.NewEvaluableExpression(e)
-	if err != nil {
-		return "", err
-	}
-	result, err := expression.Evaluate(nil)
-	if err != nil {
-		return "", err
-	}
-	address := fmt.Sprintf("0x%x", int(result.(float64)))
-	return address, nil
-}
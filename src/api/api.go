@@ -2,9 +2,12 @@ package MyApi
 
 import (
 	"MyDebugger/src/utils"
+	"encoding/binary"
 	"fmt"
 	"github.com/go-delve/delve/service/api"
 	"github.com/go-delve/delve/service/rpc2"
+	"reflect"
+	"strconv"
 	"strings"
 )
 
@@ -14,12 +17,14 @@ type CurrentStatus struct {
 	Statement int
 	// ThreadID 表示当前线程 ID
 	ThreadID int
-	// GoroutineID 表示当前所在协程 ID
+	// GoroutineID 表示当前所在协程 ID，当前线程没有运行协程时为 0
 	GoroutineID int64
 	// FilePath 表示当前源文件位置
 	FilePath string
 	// FileLine 表示当前源文件行号
 	FileLine int
+	// Function 表示当前所在的函数名
+	Function string
 	// Regs 表示寄存器
 	Regs api.Registers
 	// Rip 寄存器的值，经常需要使用
@@ -28,19 +33,53 @@ type CurrentStatus struct {
 	Rsp uint64
 	// Rbp 寄存器的值，经常需要使用
 	Rbp uint64
+	// Breakpoint 表示当前停在的断点，不是因为断点停下时为 nil
+	Breakpoint *api.Breakpoint
+	// ReturnValues 是 step-out 之后被调函数的返回值，程序再次运行之后清空，切换线程和栈帧时保留
+	ReturnValues []api.Variable
+	// NextInProgress 表示 next/step/step-out 被断点（或观察点）打断，continue 会完成它
+	NextInProgress bool
+	// Stops 是程序停下的次数，每次执行之后更新状态时加一（切换线程不算），用来标记监视器的历史记录和寄存器的变化
+	Stops int
+}
+
+// VariableLoadConfig 是读取变量时使用的配置
+var VariableLoadConfig = api.LoadConfig{
+	FollowPointers:     true,
+	MaxVariableRecurse: 1,
+	MaxStringLen:       64,
+	MaxArrayValues:     64,
+	MaxStructFields:    -1,
+}
+
+// TypeLoadConfig 是按照类型查看内存时使用的配置，会展开嵌套的结构体
+var TypeLoadConfig = api.LoadConfig{
+	FollowPointers:     true,
+	MaxVariableRecurse: 3,
+	MaxStringLen:       64,
+	MaxArrayValues:     16,
+	MaxStructFields:    -1,
 }
 
 type MyClient struct {
 	// client 是调用 rpc 的客户端
 	client  *rpc2.RPCClient
 	Current *CurrentStatus
+	// skipPatterns 是 step 时需要跳过的包和文件
+	skipPatterns []string
+	// goroot 是被调试的程序编译时的 GOROOT，gorootPath 是对应的可执行文件
+	goroot     string
+	gorootPath string
 }
 
 func NewClient(addr string) (*MyClient, error) {
 	c := new(MyClient)
 	c.client = rpc2.NewClient(addr)
+	// step-out 时让 delve 带上返回值
+	c.client.SetReturnValuesLoadConfig(&VariableLoadConfig)
 	c.Current = new(CurrentStatus)
 	c.Current.Regs = nil
+	c.skipPatterns = DefaultSkipPatterns()
 	err := c.GetStat()
 	if err != nil {
 		return nil, err
@@ -119,6 +158,30 @@ func (c *MyClient) Continue() error {
 	return c.GetStat()
 }
 
+// ContinueAsync 开始运行但不等待，程序停下并更新状态之后从返回的 channel 得到结果
+// 运行期间只能调用 Halt，其他的 rpc 要等到程序停下之后
+func (c *MyClient) ContinueAsync() <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		var last *api.DebuggerState
+		for state := range c.client.Continue() {
+			last = state
+		}
+		if last != nil && last.Err != nil {
+			done <- last.Err
+			return
+		}
+		done <- c.GetStat()
+	}()
+	return done
+}
+
+// Halt 让正在运行的程序停下，ContinueAsync 返回的 channel 会收到结果
+func (c *MyClient) Halt() error {
+	_, err := c.client.Halt()
+	return err
+}
+
 // ListRegs 得到所有的寄存器
 func (c *MyClient) ListRegs() (api.Registers, error) {
 	registers, err := c.client.ListThreadRegisters(c.Current.ThreadID, true)
@@ -129,6 +192,136 @@ func (c *MyClient) ListRegs() (api.Registers, error) {
 	return registers, nil
 }
 
+// FrameRegisterValue 取得第 frame 帧中寄存器的值，第 0 帧就是当前的寄存器，其他帧是 delve 回溯得到的
+func (c *MyClient) FrameRegisterValue(frame int, name string) (uint64, error) {
+	if frame == 0 {
+		return c.RegisterValue(name)
+	}
+	scope := c.currentEvalScope()
+	scope.Frame = frame
+	regs, err := c.client.ListScopeRegisters(scope, false)
+	if err != nil {
+		return 0, err
+	}
+	return registerValueIn(regs, name)
+}
+
+// ListThreads 列出所有的线程
+func (c *MyClient) ListThreads() ([]*api.Thread, error) {
+	return c.client.ListThreads()
+}
+
+// SwitchThread 切换当前线程，之后的寄存器、反汇编和调用栈都以该线程为准
+func (c *MyClient) SwitchThread(threadID int) error {
+	_, err := c.client.SwitchThread(threadID)
+	if err != nil {
+		return err
+	}
+	return c.loadState()
+}
+
+// RegisterValue 根据寄存器名称（不区分大小写）从当前状态中取得寄存器的值
+func (c *MyClient) RegisterValue(name string) (uint64, error) {
+	return registerValueIn(c.Current.Regs, name)
+}
+
+// registerValueIn 从 regs 中找到寄存器的值
+func registerValueIn(regs api.Registers, name string) (uint64, error) {
+	for _, reg := range regs {
+		if strings.EqualFold(reg.Name, name) {
+			// Rflags 之类的寄存器后面会带有描述，只取第一部分
+			fields := strings.Fields(reg.Value)
+			if len(fields) == 0 {
+				return 0, fmt.Errorf("register %s has no value", name)
+			}
+			return utils.StringToUint64(fields[0])
+		}
+	}
+	return 0, fmt.Errorf("unknown register %s", name)
+}
+
+// SymbolAddress 取得全局变量（符号）所在的地址
+func (c *MyClient) SymbolAddress(name string) (uint64, error) {
+	variable, err := c.client.EvalVariable(c.currentEvalScope(), name, api.LoadConfig{})
+	if err != nil {
+		return 0, err
+	}
+	return variable.Addr, nil
+}
+
+// ExecutablePath 得到被调试的程序的路径，delve 需要在本机运行
+func (c *MyClient) ExecutablePath() string {
+	return fmt.Sprintf("/proc/%d/exe", c.client.ProcessPid())
+}
+
+// QuoteTypeName 把类型名中带有 / 的包路径加上引号，这是 delve 表达式的写法
+// 例如 *net/http.Request 会变成 *"net/http".Request
+func QuoteTypeName(typeName string) string {
+	prefix := 0
+	for prefix < len(typeName) {
+		if typeName[prefix] == '*' {
+			prefix++
+		} else if strings.HasPrefix(typeName[prefix:], "[]") {
+			prefix += 2
+		} else {
+			break
+		}
+	}
+	name := typeName[prefix:]
+	slash := strings.LastIndex(name, "/")
+	if slash < 0 {
+		return typeName
+	}
+	dot := strings.Index(name[slash:], ".")
+	if dot < 0 {
+		return typeName
+	}
+	dot += slash
+	return fmt.Sprintf("%s%q%s", typeName[:prefix], name[:dot], name[dot:])
+}
+
+// EvalAddressAs 把 address 处的内存当作 typeName 类型的值读取
+func (c *MyClient) EvalAddressAs(address uint64, typeName string) (*api.Variable, error) {
+	expr := fmt.Sprintf("*(*%s)(0x%x)", QuoteTypeName(typeName), address)
+	return c.client.EvalVariable(c.currentEvalScope(), expr, TypeLoadConfig)
+}
+
+// VariableValue 取得 Go 变量的值，变量必须是整数、布尔值或者指针，指针返回它指向的地址
+func (c *MyClient) VariableValue(name string) (uint64, error) {
+	return c.VariableValueInFrame(c.Current.Statement, name)
+}
+
+// VariableValueInFrame 在第 frame 帧中取得 Go 变量的值
+func (c *MyClient) VariableValueInFrame(frame int, name string) (uint64, error) {
+	scope := c.currentEvalScope()
+	scope.Frame = frame
+	variable, err := c.client.EvalVariable(scope, name, VariableLoadConfig)
+	if err != nil {
+		return 0, err
+	}
+	if variable.Unreadable != "" {
+		return 0, fmt.Errorf("%s is unreadable: %s", name, variable.Unreadable)
+	}
+	switch variable.Kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value, err := strconv.ParseInt(variable.Value, 0, 64)
+		return uint64(value), err
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.ParseUint(variable.Value, 0, 64)
+	case reflect.Bool:
+		if variable.Value == "true" {
+			return 1, nil
+		}
+		return 0, nil
+	case reflect.Ptr, reflect.UnsafePointer:
+		if len(variable.Children) == 0 {
+			return 0, nil
+		}
+		return variable.Children[0].Addr, nil
+	}
+	return 0, fmt.Errorf("%s has type %s, not an integer or pointer", name, variable.Type)
+}
+
 func (c *MyClient) ListSource() ([]string, error) {
 	sources, err := c.client.ListSources("")
 	if err != nil {
@@ -146,6 +339,19 @@ func (c *MyClient) FindLocationByName(functionName string) (api.Location, error)
 	return locations[0], nil
 }
 
+// FunctionOfAddress 找到地址所在的函数，返回函数名和相对函数入口的偏移
+func (c *MyClient) FunctionOfAddress(address uint64) (string, uint64, error) {
+	locations, err := c.client.FindLocation(c.currentEvalScope(), fmt.Sprintf("*%#x", address), true, nil)
+	if err != nil {
+		return "", 0, err
+	}
+	if len(locations) == 0 || locations[0].Function == nil {
+		return "", 0, fmt.Errorf("address %#x does not belong to any function", address)
+	}
+	function := locations[0].Function
+	return function.Name(), address - function.Value, nil
+}
+
 // Stacktrace 列出调用栈
 func (c *MyClient) Stacktrace() []api.Stackframe {
 	stacktrace, err := c.client.Stacktrace(c.Current.GoroutineID, 10, api.StacktraceSimple, nil)
@@ -155,14 +361,60 @@ func (c *MyClient) Stacktrace() []api.Stackframe {
 	return stacktrace
 }
 
-// GetStat 更新当前状态
+// StacktraceWithVariables 列出调用栈，并读取每一帧的局部变量和参数
+func (c *MyClient) StacktraceWithVariables(depth int) ([]api.Stackframe, error) {
+	return c.client.Stacktrace(c.Current.GoroutineID, depth, api.StacktraceSimple, &VariableLoadConfig)
+}
+
+// maxFrameSearchDepth 是查找函数所在的帧时最多回溯的深度
+const maxFrameSearchDepth = 64
+
+// FindFrame 找到调用栈中最近的一个 function 所在的帧，不在调用栈中时返回 false
+func (c *MyClient) FindFrame(function string) (int, bool, error) {
+	frames, err := c.client.Stacktrace(c.Current.GoroutineID, maxFrameSearchDepth, api.StacktraceSimple, nil)
+	if err != nil {
+		return 0, false, err
+	}
+	for i, frame := range frames {
+		if frame.Function != nil && frame.Function.Name() == function {
+			return i, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// SelectFrame 选择第 n 帧，之后的求值都在该帧中进行，执行下一条命令之后恢复为第 0 帧
+func (c *MyClient) SelectFrame(n int) error {
+	frames, err := c.client.Stacktrace(c.Current.GoroutineID, n, api.StacktraceSimple, nil)
+	if err != nil {
+		return err
+	}
+	if n < 0 || n >= len(frames) {
+		return fmt.Errorf("frame %d does not exist", n)
+	}
+	c.Current.Statement = n
+	return nil
+}
+
+// GetStat 在程序停下之后更新当前状态，上一次 step-out 的返回值不再有效
 func (c *MyClient) GetStat() error {
+	c.Current.Stops++
+	c.Current.ReturnValues = nil
+	return c.loadState()
+}
+
+// loadState 从 delve 读取当前状态，不算作一次停下
+func (c *MyClient) loadState() error {
 	state, err := c.client.GetState()
 	if err != nil {
 		return err
 	}
 	c.Current.Statement = 0
+	c.Current.Breakpoint = nil
+	c.Current.NextInProgress = state.NextInProgress
 
+	// 没有运行协程的线程（例如 sysmon）的 GoroutineID 为 0，delve 会用当前线程的调用栈和寄存器求值
+	c.Current.GoroutineID = 0
 	if state.SelectedGoroutine != nil && state.SelectedGoroutine.ID > 0 {
 		c.Current.GoroutineID = state.SelectedGoroutine.ID
 	}
@@ -171,6 +423,11 @@ func (c *MyClient) GetStat() error {
 		c.Current.ThreadID = state.CurrentThread.ID
 		c.Current.FilePath = state.CurrentThread.File
 		c.Current.FileLine = state.CurrentThread.Line
+		c.Current.Breakpoint = state.CurrentThread.Breakpoint
+		c.Current.Function = ""
+		if state.CurrentThread.Function != nil {
+			c.Current.Function = state.CurrentThread.Function.Name()
+		}
 	}
 
 	regs, err := c.ListRegs()
@@ -213,33 +470,92 @@ func (c *MyClient) Next() error {
 }
 
 // NextInstruction 是汇编层面的下一步，不进入函数
-// 两种实现方法：
-//  1. 判断指令是不是call，是的话就多执行一步 step-out
-//  2. 在下一条指令下断点
-//
-// 当前使用：方法2
+// 只有 call 指令才在返回地址下临时断点并 continue，其余指令（包括 ret 和 jmp）都直接单步执行
+// 临时断点限定在当前协程和当前栈帧，避免递归调用或其他协程先命中断点
 func (c *MyClient) NextInstruction() error {
 	start := c.Current.Rip
+	// x86 指令最长 15 个字节
 	asms, err := c.Disassembly2(start, start+0x10)
 	if err != nil {
 		return err
 	}
-	var bPC uint64
-	for i, asm := range asms {
-		if asm.Loc.PC == c.Current.Rip {
-			bPC = asms[i+1].Loc.PC
-			err = c.CreateBreakpointByAddress(bPC, "")
-			if err != nil {
-				return err
-			}
-			break
+	if len(asms) == 0 || asms[0].Loc.PC != start || !isCallInstruction(asms[0].Text) {
+		return c.StepInstruction()
+	}
+	returnPC := start + uint64(len(asms[0].Bytes))
+
+	frames, err := c.client.Stacktrace(c.Current.GoroutineID, 0, api.StacktraceSimple, nil)
+	if err != nil {
+		return err
+	}
+	if len(frames) == 0 {
+		return fmt.Errorf("no stack frame for goroutine %d", c.Current.GoroutineID)
+	}
+	return c.continueToAddress(returnPC, c.goroutineCondition(fmt.Sprintf("runtime.frameoff == %d", frames[0].FrameOffset)))
+}
+
+// RunUntil 运行到 location 处，使用只对当前协程生效的一次性断点
+// location 可以是地址，也可以是 delve 支持的位置，例如函数名或者 file:line
+func (c *MyClient) RunUntil(location string) error {
+	address, err := utils.StringToUint64(location)
+	if err != nil {
+		loc, err := c.FindLocationByName(location)
+		if err != nil {
+			return err
 		}
+		address = loc.PC
 	}
-	err = c.Continue()
+	return c.continueToAddress(address, c.goroutineCondition(""))
+}
+
+// goroutineCondition 返回限定在当前协程的断点条件，extra 是附加的条件
+// 当前线程没有运行协程时 runtime.curg 为 nil，不能用协程限定，只使用 extra（可能命中其他线程）
+func (c *MyClient) goroutineCondition(extra string) string {
+	if c.Current.GoroutineID == 0 {
+		return extra
+	}
+	cond := fmt.Sprintf("runtime.curg.goid == %d", c.Current.GoroutineID)
+	if extra != "" {
+		cond += " && " + extra
+	}
+	return cond
+}
+
+// continueToAddress 在 address 处下一个带条件的临时断点并 continue
+// 无论是命中临时断点还是被其他断点中断，停下之后都会删除临时断点
+func (c *MyClient) continueToAddress(address uint64, cond string) error {
+	// 地址处已经有断点的话，直接 continue 即可
+	points, err := c.ListBreakpoints()
+	if err != nil {
+		return err
+	}
+	for _, point := range points {
+		if point.Addr == address {
+			return c.Continue()
+		}
+	}
+
+	point, err := c.client.CreateBreakpoint(&api.Breakpoint{
+		Addr: address,
+		Cond: cond,
+	})
 	if err != nil {
 		return err
 	}
-	return c.ClearBreakpointByAddress(bPC)
+	err = c.Continue()
+	if _, clearErr := c.client.ClearBreakpoint(point.ID); err == nil {
+		err = clearErr
+	}
+	// 命中的是临时断点的话，不算作停在断点处
+	if c.Current.Breakpoint != nil && c.Current.Breakpoint.ID == point.ID {
+		c.Current.Breakpoint = nil
+	}
+	return err
+}
+
+// isCallInstruction 判断是不是 call 指令
+func isCallInstruction(text string) bool {
+	return text == "call" || strings.HasPrefix(text, "call ")
 }
 
 // Disassembly 是反汇编 Rip 寄存器附近的数据
@@ -267,13 +583,30 @@ func (c *MyClient) Disassembly2(start, ends uint64) (api.AsmInstructions, error)
 	return c.client.DisassembleRange(c.currentEvalScope(), start, ends, api.IntelFlavour)
 }
 
-// StepInstruction 是汇编层面的单步运行
+// DisassemblyFunction 是反汇编整个函数，从函数入口到结尾
+// 返回值中的 uint64 是序言结束（函数体开始）的地址
+func (c *MyClient) DisassemblyFunction(functionName string) (api.AsmInstructions, uint64, error) {
+	location, err := c.FindLocationByName(functionName)
+	if err != nil {
+		return nil, 0, err
+	}
+	// DisassemblePC 会反汇编 pc 所在的整个函数
+	asms, err := c.client.DisassemblePC(c.currentEvalScope(), location.PC, api.IntelFlavour)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(asms) == 0 {
+		return nil, 0, fmt.Errorf("function %s has no instructions", functionName)
+	}
+	return asms, location.PC, nil
+}
+
+// StepInstruction 是汇编层面的单步运行，只执行一条指令
 func (c *MyClient) StepInstruction() error {
 	_, err := c.client.StepInstruction()
 	if err != nil {
 		return err
 	}
-
 	return c.GetStat()
 }
 
@@ -286,13 +619,46 @@ func (c *MyClient) Step() error {
 	return c.GetStat()
 }
 
-// StepOut 是跳出函数，会直接执行到调用者
+// StepOut 是跳出函数，会直接执行到调用者，并保存被调函数的返回值
 func (c *MyClient) StepOut() error {
-	_, err := c.client.StepOut()
+	state, err := c.client.StepOut()
 	if err != nil {
 		return err
 	}
-	return c.GetStat()
+	err = c.GetStat()
+	if err != nil {
+		return err
+	}
+	if state.CurrentThread != nil {
+		c.Current.ReturnValues = state.CurrentThread.ReturnValues
+	}
+	return nil
+}
+
+// Repeat 重复执行 step 至多 count 次，中途遇到断点、stop 返回 true 或者出错时提前停止
+// 单步执行到有断点的地址上时 delve 不会报告命中断点，所以也要检查 Rip
+// stop 在每一步之后调用，可以为 nil，返回实际执行的次数
+func (c *MyClient) Repeat(step func() error, count int, stop func() bool) (int, error) {
+	points, err := c.ListBreakpoints()
+	if err != nil {
+		return 0, err
+	}
+	addresses := make(map[uint64]bool)
+	for _, point := range points {
+		if point.ID > 0 {
+			addresses[point.Addr] = true
+		}
+	}
+	for i := 0; i < count; i++ {
+		err = step()
+		if err != nil {
+			return i, err
+		}
+		if c.Current.Breakpoint != nil || addresses[c.Current.Rip] || (stop != nil && stop()) {
+			return i + 1, nil
+		}
+	}
+	return count, nil
 }
 
 // ReadSourceCode 是读取源文件，并定位到行
@@ -300,12 +666,28 @@ func (c *MyClient) ReadSourceCode() ([]string, error) {
 	return utils.ReadSourceCodeFromFile(c.Current.FilePath, c.Current.FileLine)
 }
 
-// ExamineMemory 用来读取特定地址的 n 个字节的数据
+// ExamineMemory 用来读取特定地址的 n 个字节的数据，n 会向上对齐到 16 个字节
+// 超过 MaxExamineChunk 的读取会分成多次 rpc
 func (c *MyClient) ExamineMemory(address uint64, count int) ([]byte, error) {
 	if count%0x10 != 0 {
 		c := 0x10 - count%0x10
 		count += c
 	}
+	return ReadMemoryChunked(c.examineMemory, address, count)
+}
+
+// ReadMemory 读取从 address 开始的恰好 count 个字节，超过 MaxExamineChunk 的读取会分成多次 rpc
+func (c *MyClient) ReadMemory(address uint64, count int) ([]byte, error) {
+	return ReadMemoryChunked(c.examineMemory, address, count)
+}
+
+// ReadMemoryRanges 读取从 address 开始的 count 个字节，跳过不能读取的部分，见 ReadMemoryRanges
+func (c *MyClient) ReadMemoryRanges(address uint64, count int) ([]MemoryRange, []AddressRange) {
+	return ReadMemoryRanges(c.examineMemory, address, count)
+}
+
+// examineMemory 是一次 rpc 的读取，count 不能超过 MaxExamineChunk
+func (c *MyClient) examineMemory(address uint64, count int) ([]byte, error) {
 	memories, _, err := c.client.ExamineMemory(address, count)
 	if err != nil {
 		return nil, err
@@ -313,6 +695,39 @@ func (c *MyClient) ExamineMemory(address uint64, count int) ([]byte, error) {
 	return memories, nil
 }
 
+// WriteMemory 把 data 写入 address 开始的内存
+// delve 没有直接写内存的接口，这里通过给 *(*uint64)(addr) 这样的表达式赋值来实现，每次写 8 个字节，剩下的逐字节写
+func (c *MyClient) WriteMemory(address uint64, data []byte) error {
+	scope := c.currentEvalScope()
+	for offset := 0; offset < len(data); {
+		var symbol, value string
+		if len(data)-offset >= 8 {
+			symbol = fmt.Sprintf("*(*uint64)(0x%x)", address+uint64(offset))
+			value = fmt.Sprintf("0x%x", binary.LittleEndian.Uint64(data[offset:]))
+			offset += 8
+		} else {
+			symbol = fmt.Sprintf("*(*uint8)(0x%x)", address+uint64(offset))
+			value = fmt.Sprintf("0x%x", data[offset])
+			offset++
+		}
+		err := c.client.SetVariable(scope, symbol, value)
+		if err != nil {
+			return fmt.Errorf("write memory at %s: %w", symbol, err)
+		}
+	}
+	return nil
+}
+
+// CreateWatchpoint 在 expr 表示的内存上设置硬件观察点，写入时停下，返回断点的 ID
+// expr 需要能取地址并且大小是 1、2、4 或 8 个字节，例如 *(*uint32)(0xc000012345)
+func (c *MyClient) CreateWatchpoint(expr string) (int, error) {
+	point, err := c.client.CreateWatchpoint(c.currentEvalScope(), expr, api.WatchWrite)
+	if err != nil {
+		return 0, err
+	}
+	return point.ID, nil
+}
+
 // ClearBreakpointByName 是根据断点名消除断点
 func (c *MyClient) ClearBreakpointByName(name string) error {
 	var err error
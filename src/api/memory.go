@@ -0,0 +1,81 @@
+package MyApi
+
+import "fmt"
+
+// MaxExamineChunk 是一次 rpc 最多读取的字节数
+// delve 拒绝超过 1000 个字节的 ExamineMemory 请求，这里取不超过 1000 的 16 的倍数
+const MaxExamineChunk = 992
+
+// memoryPageSize 是内存页的大小，分段读取时不跨页，一个页读取失败不会影响相邻的页
+const memoryPageSize = 0x1000
+
+// MemoryReader 一次读取从 address 开始的 count 个字节，count 不超过 MaxExamineChunk
+type MemoryReader func(address uint64, count int) ([]byte, error)
+
+// MemoryRange 是一段连续的、可以读取的内存
+type MemoryRange struct {
+	Address uint64
+	Data    []byte
+}
+
+// AddressRange 是一段地址 [Start, End)
+type AddressRange struct {
+	Start uint64
+	End   uint64
+}
+
+// nextChunk 返回从 address 开始的下一段的结束地址，不超过 MaxExamineChunk 个字节，也不跨页
+func nextChunk(address, end uint64) uint64 {
+	next := address + MaxExamineChunk
+	if page := address&^(memoryPageSize-1) + memoryPageSize; page < next {
+		next = page
+	}
+	if next > end {
+		next = end
+	}
+	return next
+}
+
+// ReadMemoryChunked 分段读取从 address 开始的 count 个字节，任何一段读取失败都返回错误
+func ReadMemoryChunked(read MemoryReader, address uint64, count int) ([]byte, error) {
+	result := make([]byte, 0, count)
+	end := address + uint64(count)
+	for chunk := address; chunk < end; {
+		next := nextChunk(chunk, end)
+		data, err := read(chunk, int(next-chunk))
+		if err != nil {
+			return nil, fmt.Errorf("read memory at 0x%x: %w", chunk, err)
+		}
+		if len(data) < int(next-chunk) {
+			return nil, fmt.Errorf("read memory at 0x%x: got 0x%x bytes, expected 0x%x", chunk, len(data), next-chunk)
+		}
+		result = append(result, data[:next-chunk]...)
+		chunk = next
+	}
+	return result, nil
+}
+
+// ReadMemoryRanges 分段读取从 address 开始的 count 个字节，跳过读取失败的段
+// 返回读到的连续内存和读取失败的地址范围，相邻的段会合并在一起
+func ReadMemoryRanges(read MemoryReader, address uint64, count int) ([]MemoryRange, []AddressRange) {
+	readable := make([]MemoryRange, 0)
+	unreadable := make([]AddressRange, 0)
+	end := address + uint64(count)
+	for chunk := address; chunk < end; {
+		next := nextChunk(chunk, end)
+		data, err := read(chunk, int(next-chunk))
+		if err != nil || len(data) < int(next-chunk) {
+			if last := len(unreadable) - 1; last >= 0 && unreadable[last].End == chunk {
+				unreadable[last].End = next
+			} else {
+				unreadable = append(unreadable, AddressRange{Start: chunk, End: next})
+			}
+		} else if last := len(readable) - 1; last >= 0 && readable[last].Address+uint64(len(readable[last].Data)) == chunk {
+			readable[last].Data = append(readable[last].Data, data[:next-chunk]...)
+		} else {
+			readable = append(readable, MemoryRange{Address: chunk, Data: append([]byte(nil), data[:next-chunk]...)})
+		}
+		chunk = next
+	}
+	return readable, unreadable
+}
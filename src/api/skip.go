@@ -0,0 +1,156 @@
+package MyApi
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// StdPattern 表示所有的标准库（包括 runtime）
+const StdPattern = "std"
+
+// filePatternPrefix 是文件匹配规则的前缀，例如 file:*_test.go
+const filePatternPrefix = "file:"
+
+// maxSkipSteps 是为了跳过函数最多执行 step-out 的次数，防止死循环
+const maxSkipSteps = 64
+
+// DefaultSkipPatterns 是默认的跳过列表，跳过 runtime 和标准库
+func DefaultSkipPatterns() []string {
+	return []string{StdPattern}
+}
+
+// PackageOfFunction 从函数全名中得到包路径，例如 net/http.(*Server).Serve 的包路径是 net/http
+func PackageOfFunction(functionName string) string {
+	slash := strings.LastIndex(functionName, "/")
+	dot := strings.Index(functionName[slash+1:], ".")
+	if dot == -1 {
+		return functionName
+	}
+	return functionName[:slash+1+dot]
+}
+
+// isStdFile 判断文件是不是标准库的源码，标准库的源码都在 $GOROOT/src 下
+// goroot 为空（例如使用 -trimpath 编译，无法得到 GOROOT）时不把任何文件当作标准库
+func isStdFile(file, goroot string) bool {
+	if file == "" || goroot == "" {
+		return false
+	}
+	return strings.HasPrefix(filepath.ToSlash(file), strings.TrimSuffix(filepath.ToSlash(goroot), "/")+"/src/")
+}
+
+// GorootOfFile 从 runtime 包的源文件路径得到编译时的 GOROOT，例如 /usr/local/go/src/runtime/proc.go 得到 /usr/local/go
+func GorootOfFile(runtimeFile string) string {
+	index := strings.LastIndex(filepath.ToSlash(runtimeFile), "/src/runtime/")
+	if index <= 0 {
+		return ""
+	}
+	return runtimeFile[:index]
+}
+
+// MatchSkipPattern 判断函数或者文件是否匹配跳过规则
+// 规则可以是 std、包路径的 glob（例如 github.com/foo/*），或者 file: 开头的文件 glob
+// std 根据文件是否在被调试程序的 goroot 下判断
+func MatchSkipPattern(pattern, functionName, file, goroot string) bool {
+	if strings.HasPrefix(pattern, filePatternPrefix) {
+		pattern = strings.TrimPrefix(pattern, filePatternPrefix)
+		if ok, _ := filepath.Match(pattern, file); ok {
+			return true
+		}
+		ok, _ := filepath.Match(pattern, filepath.Base(file))
+		return ok
+	}
+	if pattern == StdPattern {
+		return isStdFile(file, goroot)
+	}
+	ok, _ := path.Match(pattern, PackageOfFunction(functionName))
+	return ok
+}
+
+// SkipPatterns 返回当前的跳过列表
+func (c *MyClient) SkipPatterns() []string {
+	return c.skipPatterns
+}
+
+// AddSkipPattern 添加一条跳过规则
+func (c *MyClient) AddSkipPattern(pattern string) error {
+	if _, err := path.Match(strings.TrimPrefix(pattern, filePatternPrefix), ""); err != nil {
+		return err
+	}
+	for _, p := range c.skipPatterns {
+		if p == pattern {
+			return nil
+		}
+	}
+	c.skipPatterns = append(c.skipPatterns, pattern)
+	return nil
+}
+
+// RemoveSkipPattern 删除一条跳过规则
+func (c *MyClient) RemoveSkipPattern(pattern string) error {
+	for i, p := range c.skipPatterns {
+		if p == pattern {
+			c.skipPatterns = append(c.skipPatterns[:i], c.skipPatterns[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("skip pattern %s not found", pattern)
+}
+
+// ClearSkipPatterns 清空跳过列表，step 会进入所有函数
+func (c *MyClient) ClearSkipPatterns() {
+	c.skipPatterns = nil
+}
+
+// ResetSkipPatterns 恢复默认的跳过列表
+func (c *MyClient) ResetSkipPatterns() {
+	c.skipPatterns = DefaultSkipPatterns()
+}
+
+// targetGoroot 得到被调试的程序编译时的 GOROOT，从 runtime.main 所在的文件推断，程序没有变化时使用缓存
+func (c *MyClient) targetGoroot() string {
+	path := c.ExecutablePath()
+	if c.gorootPath == path {
+		return c.goroot
+	}
+	location, err := c.FindLocationByName("runtime.main")
+	if err != nil {
+		return ""
+	}
+	c.goroot, c.gorootPath = GorootOfFile(location.File), path
+	return c.goroot
+}
+
+// shouldSkip 判断当前所在的函数是否在跳过列表中
+func (c *MyClient) shouldSkip() bool {
+	goroot := c.targetGoroot()
+	for _, pattern := range c.skipPatterns {
+		if MatchSkipPattern(pattern, c.Current.Function, c.Current.FilePath, goroot) {
+			return true
+		}
+	}
+	return false
+}
+
+// StepSkipping 执行 step，如果因此进入了跳过列表中的函数，就一直 step-out，直到回到用户自己的代码
+// 执行之前就已经在跳过列表中的函数里的话，不会跳出；中途遇到断点时停下
+// step-out 会执行完整个函数，所以被跳过的函数回调的用户代码（例如 fmt.Println 调用的 String 方法）也会被跳过，
+// 只有其中的断点能让程序停下
+// 只用于用户的 s/si 命令，跟踪器等需要精确单步的地方直接调用 Step/StepInstruction
+func (c *MyClient) StepSkipping(step func() error) error {
+	skipped := c.shouldSkip()
+	err := step()
+	if err != nil || skipped {
+		return err
+	}
+	for i := 0; i < maxSkipSteps && c.Current.Breakpoint == nil && c.shouldSkip(); i++ {
+		err = c.StepOut()
+		if err != nil {
+			return err
+		}
+	}
+	// 自动跳出的函数的返回值不需要显示
+	c.Current.ReturnValues = nil
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"MyDebugger/src/TUI/UI"
+	"testing"
+)
+
+func TestParseMemoryOperand(t *testing.T) {
+	operand, ok := UI.ParseMemoryOperand("mov rax, qword ptr [rbx+rcx*8-0x18]")
+	if !ok {
+		t.Fatal("operand not found")
+	}
+	if operand.Size != 8 || operand.Base != "rbx" || operand.Index != "rcx" || operand.Scale != 8 || operand.Disp != -0x18 {
+		t.Fatalf("%+v", operand)
+	}
+
+	operand, ok = UI.ParseMemoryOperand("mov rcx, qword ptr fs:[0xfffffff8]")
+	if !ok {
+		t.Fatal("operand not found")
+	}
+	if operand.Segment != "fs" || operand.Base != "" || operand.Disp != 0xfffffff8 {
+		t.Fatalf("%+v", operand)
+	}
+
+	operand, ok = UI.ParseMemoryOperand("mov eax, dword ptr [main.counter+8]")
+	if !ok {
+		t.Fatal("operand not found")
+	}
+	if operand.Size != 4 || operand.Symbol != "main.counter" || operand.Disp != 8 {
+		t.Fatalf("%+v", operand)
+	}
+
+	if _, ok = UI.ParseMemoryOperand("add rax, rbx"); ok {
+		t.Fatal("unexpected operand")
+	}
+}
+
+func TestJumpTaken(t *testing.T) {
+	const zf, cf, sf, of = 1 << 6, 1 << 0, 1 << 7, 1 << 11
+	cases := []struct {
+		mnemonic string
+		rflags   uint64
+		taken    bool
+	}{
+		{"je", zf, true},
+		{"jne", zf, false},
+		{"jbe", cf, true},
+		{"ja", 0, true},
+		{"jl", sf, true},
+		{"jl", sf | of, false},
+		{"jg", of, false},
+		{"jbe.pn", 0, false},
+	}
+	for _, c := range cases {
+		taken, ok := UI.JumpTaken(c.mnemonic, c.rflags, 0)
+		if !ok || taken != c.taken {
+			t.Fatalf("%s with rflags 0x%x: taken=%v ok=%v", c.mnemonic, c.rflags, taken, ok)
+		}
+	}
+	if _, ok := UI.JumpTaken("jmp", 0, 0); ok {
+		t.Fatal("jmp is not a conditional jump")
+	}
+}
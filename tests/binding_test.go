@@ -0,0 +1,44 @@
+package main
+
+import (
+	"MyDebugger/src/TUI/UI"
+	"testing"
+)
+
+func TestUsesFrame(t *testing.T) {
+	cases := map[string]bool{
+		"0xc000010000":           false,
+		"&runtime.sched + 8":     false,
+		"main.count":             false,
+		"*(u64*)(&main.x)":       false,
+		"$rsp+8":                 true,
+		"*(u32*)(buf + 4)":       true,
+		"*(u64*)($rbp-8) + 0x10": true,
+	}
+	for expr, want := range cases {
+		if got := UI.UsesFrame(expr); got != want {
+			t.Fatal(expr, got)
+		}
+	}
+}
+
+func TestParseBinding(t *testing.T) {
+	binding, rest, err := UI.ParseBinding([]string{"$rsp+8", "8"}, "main.f")
+	if err != nil || binding.Scope != "main.f" || len(rest) != 1 || rest[0] != "8" {
+		t.Fatal(binding, rest, err)
+	}
+	if binding.String() != "$rsp+8 @main.f" {
+		t.Fatal(binding.String())
+	}
+	binding, _, _ = UI.ParseBinding([]string{"--global", "$rsp+8"}, "main.f")
+	if binding.Scope != "" {
+		t.Fatal(binding)
+	}
+	binding, _, _ = UI.ParseBinding([]string{"&main.x", "--in", "main.g"}, "main.f")
+	if binding.Scope != "main.g" || binding.Expr != "&main.x" {
+		t.Fatal(binding)
+	}
+	if _, _, err = UI.ParseBinding([]string{"--in"}, "main.f"); err == nil {
+		t.Fatal("--in without function should be an error")
+	}
+}
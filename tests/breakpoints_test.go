@@ -440,10 +440,10 @@ func TestPrintArray(t *testing.T) {
 
 func Test(t *testing.T) {
 	tString := "0x20+0x30+20"
-	args, err := UI.ParseArgs(tString)
+	value, err := UI.EvaluateExpression(tString, nil)
 	if err != nil {
 		t.Fatal(err)
 		return
 	}
-	fmt.Println(args)
+	fmt.Println(value)
 }
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"MyDebugger/src/TUI/UI"
+	"github.com/go-delve/delve/service/api"
+	"strings"
+	"testing"
+)
+
+func newAsm(pc uint64, text string) api.AsmInstruction {
+	return api.AsmInstruction{
+		Loc: api.Location{
+			PC:       pc,
+			Function: &api.Function{Name_: "main.add"},
+		},
+		Text: text,
+	}
+}
+
+func TestFormatFunctionASM(t *testing.T) {
+	asms := api.AsmInstructions{
+		newAsm(0x1000, "cmp rsp, qword ptr [r14+0x10]"),
+		newAsm(0x1004, "jbe 0x1020"),
+		newAsm(0x1006, "push rbp"),
+		newAsm(0x1007, "mov rbp, rsp"),
+		newAsm(0x100a, "sub rsp, 0x10"),
+		newAsm(0x100e, "add rax, rbx"),
+		newAsm(0x1011, "add rsp, 0x10"),
+		newAsm(0x1015, "pop rbp"),
+		newAsm(0x1016, "ret"),
+		newAsm(0x1020, "call $runtime.morestack_noctxt"),
+		newAsm(0x1025, "jmp 0x1000"),
+	}
+	lines := UI.FormatFunctionASM(asms, 0x100e, 0x100e)
+	markers := make([]string, 0)
+	for _, line := range lines {
+		if strings.Contains(line, "; ----") {
+			markers = append(markers, line)
+		}
+	}
+	expected := []string{"prologue", "body", "epilogue", "morestack"}
+	if len(markers) != len(expected) {
+		t.Fatalf("got markers %v", markers)
+	}
+	for i, marker := range markers {
+		if !strings.Contains(marker, expected[i]) {
+			t.Fatalf("marker %d is %s, want %s", i, marker, expected[i])
+		}
+	}
+	if len(lines) != len(asms)+len(expected)+1 {
+		t.Fatalf("got %d lines", len(lines))
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"MyDebugger/src/TUI/UI"
+	"testing"
+)
+
+func TestDisplayType(t *testing.T) {
+	cases := []struct {
+		typ  string
+		data []byte
+		want string
+	}{
+		{"4", []byte{0x78, 0x56, 0x34, 0x12}, "0x12345678"},
+		{"i16", []byte{0xfe, 0xff}, "-2"},
+		{"u16", []byte{0xfe, 0xff}, "65534"},
+		{"f32", []byte{0, 0, 0xc0, 0x3f}, "1.5"},
+		{"f64", []byte{0, 0, 0, 0, 0, 0, 0xf0, 0xbf}, "-1"},
+		{"bool", []byte{1}, "true"},
+		{"str:8", []byte("hi[red]\x00x"), `"hi[red[]"`},
+	}
+	for _, c := range cases {
+		display, err := UI.ParseDisplayType(c.typ)
+		if err != nil {
+			t.Fatal(c.typ, err)
+		}
+		if got := display.FormatValue(c.data); got != c.want {
+			t.Fatal(c.typ, got)
+		}
+	}
+	if display, err := UI.ParseDisplayType("*net/http.Request"); err != nil || display.Size != 0 {
+		t.Fatal(display, err)
+	}
+	if _, err := UI.ParseDisplayType("float"); err == nil {
+		t.Fatal("unknown type should be an error")
+	}
+}
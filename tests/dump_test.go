@@ -0,0 +1,22 @@
+package main
+
+import (
+	"MyDebugger/src/TUI/UI"
+	"testing"
+)
+
+func TestEncodeMemoryDump(t *testing.T) {
+	data := []byte{0x0a, 0x03, 'a', 'b', 'c'}
+	raw, err := UI.EncodeMemoryDump(data, 0x1000, "raw")
+	if err != nil || string(raw) != string(data) {
+		t.Fatal(raw, err)
+	}
+	literal, err := UI.EncodeMemoryDump(data, 0x1000, "go")
+	want := "// 0x1000, 5 bytes\n[]byte{\n\t0x0a, 0x03, 0x61, 0x62, 0x63,\n}\n"
+	if err != nil || string(literal) != want {
+		t.Fatal(string(literal), err)
+	}
+	if _, err = UI.EncodeMemoryDump(data, 0x1000, "base64"); err == nil {
+		t.Fatal("unknown format should be an error")
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"MyDebugger/src/TUI/UI"
+	"strings"
+	"testing"
+)
+
+func TestParseExamineSpec(t *testing.T) {
+	last := UI.ExamineSpec{Format: 'x', Size: 1}
+	spec, err := UI.ParseExamineSpec("/10dg", last)
+	if err != nil || spec.Count != 10 || spec.Format != 'd' || spec.Size != 8 {
+		t.Fatal(spec, err)
+	}
+	// 兼容以前的大小在前的写法，没有指定的沿用上一次的
+	spec, err = UI.ParseExamineSpec("w", UI.ExamineSpec{Format: 'u', Size: 1})
+	if err != nil || spec.Count != 0 || spec.Format != 'u' || spec.Size != 4 {
+		t.Fatal(spec, err)
+	}
+	spec, _ = UI.ParseExamineSpec("/2fb", last)
+	if spec.Size != 8 {
+		t.Fatal("float format should use 8 bytes for b")
+	}
+	if _, err = UI.ParseExamineSpec("/4q", last); err == nil {
+		t.Fatal("unknown letter should be an error")
+	}
+}
+
+func TestFormatMemory(t *testing.T) {
+	data := []byte("hello, world!\x00\xff\xfe")
+	lines := UI.FormatMemory(data, 0x1000, UI.ExamineSpec{Count: 16, Format: 'x', Size: 1})
+	if len(lines) != 2 {
+		t.Fatal(strings.Join(lines, "\n"))
+	}
+	if !strings.HasSuffix(lines[0], "|hello, w|") || !strings.HasSuffix(lines[1], "|orld!...|") {
+		t.Fatal(strings.Join(lines, "\n"))
+	}
+	lines = UI.FormatMemory(data[14:], 0x100e, UI.ExamineSpec{Count: 1, Format: 'd', Size: 2})
+	if lines[0] != "0x100e    -257" {
+		t.Fatal(lines[0])
+	}
+	lines = UI.FormatMemory([]byte{5}, 0x10, UI.ExamineSpec{Count: 1, Format: 't', Size: 1})
+	if lines[0] != "0x10    00000101" {
+		t.Fatal(lines[0])
+	}
+	lines = UI.FormatMemory([]byte{0, 0, 0, 0, 0, 0, 0xf0, 0x3f}, 0x10, UI.ExamineSpec{Count: 1, Format: 'f', Size: 8})
+	if lines[0] != "0x10    1" {
+		t.Fatal(lines[0])
+	}
+	// 最后一行不满的时候也要对齐 ASCII
+	lines = UI.FormatMemory([]byte("abcdefghij"), 0, UI.ExamineSpec{Count: 10, Format: 'x', Size: 1})
+	if strings.Index(lines[0], "|") != strings.Index(lines[1], "|") {
+		t.Fatal(strings.Join(lines, "\n"))
+	}
+}
+
+func TestFormatStrings(t *testing.T) {
+	lines := UI.FormatStrings([]byte("abc\x00de\x00fgh"), 0x20, 3)
+	if len(lines) != 3 || lines[1] != `0x24    "de"` || lines[2] != `0x27    "fgh"...` {
+		t.Fatal(strings.Join(lines, "\n"))
+	}
+}
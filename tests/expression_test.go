@@ -0,0 +1,126 @@
+package main
+
+import (
+	"MyDebugger/src/TUI/UI"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// fakeEnv 是测试用的求值环境
+type fakeEnv struct {
+	memory map[uint64][]byte
+}
+
+func (e fakeEnv) Register(name string) (uint64, error) {
+	switch name {
+	case "rsp":
+		return 0xc000040f00, nil
+	case "rax":
+		return 0xffffffffffffffff, nil
+	}
+	return 0, fmt.Errorf("unknown register %s", name)
+}
+
+func (e fakeEnv) ReadMemory(address uint64, size int) ([]byte, error) {
+	data, ok := e.memory[address]
+	if !ok {
+		return nil, fmt.Errorf("unmapped")
+	}
+	return data[:size], nil
+}
+
+func (e fakeEnv) Symbol(name string) (uint64, error) {
+	if name == "runtime.sched" {
+		return 0x5a0000, nil
+	}
+	return 0, fmt.Errorf("not found")
+}
+
+func (e fakeEnv) Variable(name string) (uint64, error) {
+	if name == "main.count" {
+		return 3, nil
+	}
+	return 0, fmt.Errorf("not found")
+}
+
+func TestEvaluateExpression(t *testing.T) {
+	env := fakeEnv{memory: map[uint64][]byte{
+		0xc000040f08: binary.LittleEndian.AppendUint64(nil, 0xfffffffffffffff0),
+	}}
+	cases := map[string]uint64{
+		"0x10 + 2*3":               0x16,
+		"$RSP+8":                   0xc000040f08,
+		"$rax":                     0xffffffffffffffff,
+		"$rax - 1":                 0xfffffffffffffffe,
+		"*(u64*)($rsp+8)":          0xfffffffffffffff0,
+		"*(u8*)($rsp+8)":           0xf0,
+		"*(i8*)($rsp+8)":           0xfffffffffffffff0,
+		"*($rsp+8) >> 60":          0xf,
+		"&runtime.sched + 0x10":    0x5a0010,
+		"main.count << 4 | 1":      0x31,
+		"~0 ^ 0xff & 0x0f":         0xfffffffffffffff0,
+		"(1 + 2) * 3 % 5":          4,
+		"-1":                       0xffffffffffffffff,
+		"0b101 + 0o7":              12,
+		"18446744073709551615 - 1": 0xfffffffffffffffe,
+	}
+	for expr, want := range cases {
+		got, err := UI.EvaluateExpression(expr, env)
+		if err != nil || got != want {
+			t.Fatalf("%s: got 0x%x, %v", expr, got, err)
+		}
+	}
+}
+
+func TestExpressionErrors(t *testing.T) {
+	env := fakeEnv{}
+	cases := map[string]int{
+		"$rsp + $foo": 7,
+		"1 +":         3,
+		"(1 + 2":      6,
+		"1 / 0":       2,
+		"*(u128*)1":   2,
+		"1 # 2":       2,
+		"0x1g":        0,
+	}
+	for expr, pos := range cases {
+		_, err := UI.EvaluateExpression(expr, env)
+		exprErr, ok := err.(*UI.ExprError)
+		if !ok || exprErr.Pos != pos {
+			t.Fatalf("%s: %v", expr, err)
+		}
+		// 最后一行是指向出错位置的 ^
+		lines := strings.Split(err.Error(), "\n")
+		if lines[len(lines)-1] != strings.Repeat(" ", pos)+"^" {
+			t.Fatal(err.Error())
+		}
+	}
+}
+
+func TestSplitCommand(t *testing.T) {
+	cases := map[string][]string{
+		"x/4xg ($rsp + 8)":                      {"x/4xg", "($rsp + 8)"},
+		"m  *(*uint64)($rbp - 8)   u64 --break": {"m", "*(*uint64)($rbp - 8)", "u64", "--break"},
+		"monitor &x i32 --when '> 100'":         {"monitor", "&x", "i32", "--when", "'> 100'"},
+		`find $rsp +0x100 "ab c"`:               {"find", "$rsp", "+0x100", `"ab c"`},
+		"c":                                     {"c"},
+		"  ":                                    {},
+	}
+	for command, want := range cases {
+		got := UI.SplitCommand(command)
+		if strings.Join(got, "|") != strings.Join(want, "|") || len(got) != len(want) {
+			t.Fatalf("%q: got %q", command, got)
+		}
+	}
+	args := UI.SplitCommand("monitor x u64 --when '== \"a b\"'")
+	_, condition, _, err := UI.ParseBreakFlags(args[1:])
+	if err != nil || condition.Value != `"a b"` {
+		t.Fatal(args, condition, err)
+	}
+	address, err := UI.EvaluateExpression(UI.SplitCommand("x/4xg ($rsp + 8)")[1], fakeEnv{})
+	if err != nil || address != 0xc000040f08 {
+		t.Fatal(address, err)
+	}
+}
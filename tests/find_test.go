@@ -0,0 +1,72 @@
+package main
+
+import (
+	"MyDebugger/src/TUI/UI"
+	"fmt"
+	"testing"
+)
+
+func TestParseSearchPattern(t *testing.T) {
+	cases := map[string][]byte{
+		`"ab c"`:       []byte("ab c"),
+		`u"hi"`:        {'h', 0, 'i', 0},
+		"w:0x1234":     {0x34, 0x12, 0, 0},
+		"h:-1":         {0xff, 0xff},
+		"0xc000012345": {0x45, 0x23, 0x01, 0x00, 0xc0, 0, 0, 0},
+		"48 8b05":      {0x48, 0x8b, 0x05},
+	}
+	for pattern, want := range cases {
+		got, err := UI.ParseSearchPattern(pattern)
+		if err != nil || string(got.Bytes) != string(want) {
+			t.Fatal(pattern, got.Bytes, err)
+		}
+	}
+	if _, err := UI.ParseSearchPattern("48 8"); err == nil {
+		t.Fatal("odd length pattern should be an error")
+	}
+	for _, pattern := range []string{`""`, `u""`} {
+		if _, err := UI.ParseSearchPattern(pattern); err == nil {
+			t.Fatalf("empty pattern %s should be an error", pattern)
+		}
+	}
+}
+
+func TestSearchMemory(t *testing.T) {
+	pattern, _ := UI.ParseSearchPattern("48 ?? 05")
+	hits := UI.SearchMemory([]byte{0x48, 0x8b, 0x05, 0x48, 0x89, 0x05, 0x48}, 0x1000, pattern)
+	if len(hits) != 2 || hits[0] != 0x1000 || hits[1] != 0x1003 {
+		t.Fatal(hits)
+	}
+	start, end, err := UI.ParseSearchRange("0x1000", "+0x20")
+	if err != nil || start != 0x1000 || end != 0x1020 {
+		t.Fatal(start, end, err)
+	}
+}
+
+func TestFindInMemory(t *testing.T) {
+	// "abcd" 跨过了 0x10000 处查找分段的边界，0x12000 的页不能读取
+	read := func(address uint64, count int) ([]byte, error) {
+		if count > 1000 {
+			return nil, fmt.Errorf("len must be less than or equal to 1000")
+		}
+		data := make([]byte, count)
+		for i := range data {
+			current := address + uint64(i)
+			if current&^0xfff == 0x12000 {
+				return nil, fmt.Errorf("could not read memory at 0x%x", current)
+			}
+			if current >= 0xfffe && current < 0x10002 {
+				data[i] = "abcd"[current-0xfffe]
+			}
+		}
+		return data, nil
+	}
+	pattern, _ := UI.ParseSearchPattern(`"abcd"`)
+	hits, unreadable, truncated := UI.FindInMemory(read, 0, 0x14000, pattern)
+	if truncated || len(hits) != 1 || hits[0] != 0xfffe {
+		t.Fatal(hits, truncated)
+	}
+	if len(unreadable) != 1 || unreadable[0].Start != 0x12000 || unreadable[0].End != 0x13000 {
+		t.Fatal(unreadable)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"MyDebugger/src/TUI/UI"
+	MyApi "MyDebugger/src/api"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-delve/delve/service/api"
+)
+
+// layoutProgram 是用来读取 DWARF 的程序，go test 生成的程序没有 DWARF
+const layoutProgram = `package main
+
+type sample struct {
+	a bool
+	b int64
+	c bool
+	in inner
+}
+
+type inner struct {
+	x int32
+	y int16
+}
+
+var s sample
+
+func main() {
+	s.a = true
+	println(s.b)
+}
+`
+
+// buildLayoutProgram 编译 layoutProgram，返回可执行文件的类型索引
+func buildLayoutProgram(t *testing.T) *UI.TypeIndex {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(layoutProgram), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module sample\n\ngo 1.19\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("go", "build", "-o", "sample", ".")
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatal(string(output), err)
+	}
+	index, err := UI.LoadTypeIndex(filepath.Join(dir, "sample"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return index
+}
+
+func TestLayoutOf(t *testing.T) {
+	index := buildLayoutProgram(t)
+	typ, err := index.Lookup("main.sample")
+	if err != nil {
+		t.Fatal(err)
+	}
+	layout := UI.LayoutOf(typ)
+	if layout.Size != 32 || len(layout.Fields) != 4 {
+		t.Fatal(layout)
+	}
+	if f := layout.Fields[0]; f.Offset != 0 || f.Size != 1 || f.Padding != 7 {
+		t.Fatal(f)
+	}
+	if f := layout.Fields[2]; f.Offset != 16 || f.Padding != 3 {
+		t.Fatal(f)
+	}
+
+	variable := api.Variable{Addr: 0x1000, Kind: reflect.Struct, Children: []api.Variable{
+		{Name: "a", Kind: reflect.Bool, Value: "true"},
+		{Name: "b", Kind: reflect.Int64, Value: "5"},
+		{Name: "c", Kind: reflect.Bool, Value: "false"},
+		{Name: "in", Kind: reflect.Struct, Children: []api.Variable{
+			{Name: "x", Kind: reflect.Int32, Value: "1"},
+			{Name: "y", Kind: reflect.Int16, Value: "2"},
+		}},
+	}}
+	lines := UI.FormatTypedValue(variable, typ)
+	text := strings.Join(lines, "\n")
+	for _, want := range []string{"0x1000  main.sample  size 32", "+0x0    1    a bool = true", "(padding)", "    +0x14   4    x int32 = 1"} {
+		if !strings.Contains(text, want) {
+			t.Fatal(text)
+		}
+	}
+}
+
+func TestSuggestLayout(t *testing.T) {
+	index := buildLayoutProgram(t)
+	typ, err := index.Lookup("main.sample")
+	if err != nil {
+		t.Fatal(err)
+	}
+	layout := UI.LayoutOf(typ)
+	if layout.Align != 8 || layout.Holes() != 14 {
+		t.Fatal(layout)
+	}
+	suggested := UI.SuggestLayout(layout)
+	// b int64, in inner (8), a bool, c bool，一共 18 字节，对齐到 24
+	if suggested.Size != 24 {
+		t.Fatal(suggested)
+	}
+	names := make([]string, 0)
+	for _, field := range suggested.Fields {
+		names = append(names, field.Name)
+	}
+	if strings.Join(names, ",") != "b,in,a,c" {
+		t.Fatal(names)
+	}
+	text := strings.Join(UI.FormatLayout(layout), "\n")
+	if !strings.Contains(text, "可以节省 8 个字节") {
+		t.Fatal(text)
+	}
+}
+
+func TestQuoteTypeName(t *testing.T) {
+	cases := map[string]string{
+		"main.T":            "main.T",
+		"*net/http.Request": `*"net/http".Request`,
+		"[]*a/b/c.T":        `[]*"a/b/c".T`,
+	}
+	for name, want := range cases {
+		if got := MyApi.QuoteTypeName(name); got != want {
+			t.Fatal(name, got)
+		}
+	}
+}
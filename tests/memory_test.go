@@ -0,0 +1,64 @@
+package main
+
+import (
+	"MyDebugger/src/api"
+	"fmt"
+	"testing"
+)
+
+// fakeMemory 模拟 delve 的 ExamineMemory：一次最多读 1000 个字节，unmapped 中的页不能读取
+type fakeMemory struct {
+	unmapped map[uint64]bool
+	calls    int
+}
+
+func (m *fakeMemory) read(address uint64, count int) ([]byte, error) {
+	m.calls++
+	if count > 1000 {
+		return nil, fmt.Errorf("len must be less than or equal to 1000")
+	}
+	data := make([]byte, count)
+	for i := range data {
+		current := address + uint64(i)
+		if m.unmapped[current&^0xfff] {
+			return nil, fmt.Errorf("could not read memory at 0x%x", current)
+		}
+		data[i] = byte(current)
+	}
+	return data, nil
+}
+
+func TestReadMemoryChunked(t *testing.T) {
+	memory := &fakeMemory{}
+	data, err := MyApi.ReadMemoryChunked(memory.read, 0x1008, 0x2000)
+	if err != nil || len(data) != 0x2000 {
+		t.Fatal(len(data), err)
+	}
+	for i, b := range data {
+		if b != byte(0x1008+i) {
+			t.Fatalf("byte 0x%x: got 0x%x", i, b)
+		}
+	}
+	if memory.calls < 0x2000/MyApi.MaxExamineChunk {
+		t.Fatal("large reads should be split", memory.calls)
+	}
+	memory.unmapped = map[uint64]bool{0x2000: true}
+	if _, err = MyApi.ReadMemoryChunked(memory.read, 0x1008, 0x2000); err == nil {
+		t.Fatal("reading an unmapped page should be an error")
+	}
+}
+
+func TestReadMemoryRanges(t *testing.T) {
+	memory := &fakeMemory{unmapped: map[uint64]bool{0x2000: true, 0x3000: true}}
+	readable, unreadable := MyApi.ReadMemoryRanges(memory.read, 0x1800, 0x3000)
+	if len(readable) != 2 || readable[0].Address != 0x1800 || len(readable[0].Data) != 0x800 ||
+		readable[1].Address != 0x4000 || len(readable[1].Data) != 0x800 {
+		t.Fatal(readable)
+	}
+	if readable[1].Data[0] != 0x00 || readable[1].Data[1] != 0x01 {
+		t.Fatal(readable[1].Data[:2])
+	}
+	if len(unreadable) != 1 || unreadable[0].Start != 0x2000 || unreadable[0].End != 0x4000 {
+		t.Fatal(unreadable)
+	}
+}
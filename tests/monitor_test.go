@@ -0,0 +1,85 @@
+package main
+
+import (
+	"MyDebugger/src/TUI/UI"
+	"strings"
+	"testing"
+)
+
+func TestSparkline(t *testing.T) {
+	if got := UI.Sparkline([]float64{0, 7, 14}); got != "▁▄█" {
+		t.Fatal(got)
+	}
+	if got := UI.Sparkline([]float64{3, 3}); got != "▁▁" {
+		t.Fatal(got)
+	}
+}
+
+func TestFormatMonitorHistory(t *testing.T) {
+	history := []UI.MonitorRecord{
+		{Stop: 1, Location: "main.f main.go:10", Goroutine: 1, Value: "0x1"},
+		{Stop: 4, Location: "main.f main.go:12", Goroutine: 1, Value: "0x8"},
+	}
+	lines := UI.FormatMonitorHistory("#1 &main.x  hex4", history)
+	if len(lines) != 5 || !strings.Contains(lines[3], "main.go:12") || lines[4] != "[yellow]; ▁█[white]" {
+		t.Fatal(strings.Join(lines, "\n"))
+	}
+	// 不是数的时候不显示 sparkline
+	history[1].Value = `"abc"`
+	lines = UI.FormatMonitorHistory("#1", history)
+	if len(lines) != 4 {
+		t.Fatal(strings.Join(lines, "\n"))
+	}
+	// 停在没有协程的线程上时不显示协程号
+	history[1].Goroutine = 0
+	lines = UI.FormatMonitorHistory("#1", history)
+	if !strings.HasPrefix(lines[3], "4      -         ") {
+		t.Fatal(strings.Join(lines, "\n"))
+	}
+}
+
+func TestParseBreakFlags(t *testing.T) {
+	isBreakpoint, condition, rest, err := UI.ParseBreakFlags([]string{"&main.x", "i32", "--when", "'>", "100'", "--global"})
+	if err != nil || !isBreakpoint || condition == nil || condition.Op != ">" || condition.Value != "100" {
+		t.Fatal(isBreakpoint, condition, err)
+	}
+	if strings.Join(rest, " ") != "&main.x i32 --global" {
+		t.Fatal(rest)
+	}
+	isBreakpoint, condition, rest, err = UI.ParseBreakFlags([]string{"$rsp", "--break"})
+	if err != nil || !isBreakpoint || condition != nil || len(rest) != 1 {
+		t.Fatal(isBreakpoint, condition, rest, err)
+	}
+	if _, _, _, err = UI.ParseBreakFlags([]string{"$rsp", "--when"}); err == nil {
+		t.Fatal("expected error for missing condition")
+	}
+	if _, err = UI.ParseMonitorCondition("~ 3"); err == nil {
+		t.Fatal("expected error for unknown operator")
+	}
+}
+
+func TestMonitorConditionMatch(t *testing.T) {
+	cases := []struct {
+		condition string
+		value     string
+		want      bool
+	}{
+		{"> 100", "101", true},
+		{"> 100", "100", false},
+		{">=0x10", "16", true},
+		{"== 0x10", "0x00000010", true},
+		{"!= true", "false", true},
+		{"< 1.5", "1.25", true},
+		{`'== "abc"'`, `"abc"`, true},
+		{"> abc", "abd", false},
+	}
+	for _, c := range cases {
+		condition, err := UI.ParseMonitorCondition(c.condition)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := condition.Match(c.value); got != c.want {
+			t.Fatalf("%s on %s: got %v", c.condition, c.value, got)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"MyDebugger/src/TUI/UI"
+	"github.com/go-delve/delve/service/api"
+	"strings"
+	"testing"
+)
+
+func TestDecodeRflags(t *testing.T) {
+	if got := UI.DecodeRflags(0x246); got != "[PF ZF IF IOPL=0]" {
+		t.Fatal(got)
+	}
+	if got := UI.DecodeMxcsr(0x1f80); got != "[IM DM ZM OM UM PM RC=nearest]" {
+		t.Fatal(got)
+	}
+}
+
+func TestRegsToStrings(t *testing.T) {
+	regs := api.Registers{
+		{Name: "Rip", Value: "0x0000000000401000"},
+		{Name: "Rsp", Value: "0x000000c000040f00"},
+		{Name: "Rax", Value: "0x0000000000000001"},
+		{Name: "XMM0", Value: "0x0 v2_int={ 0 0 }"},
+		{Name: "Rflags", Value: "0x0000000000000246\t[PF ZF IF IOPL=0]"},
+		{Name: "Fs_base", Value: "0x00007f0000000000"},
+	}
+	lines := UI.RegsToStrings(regs, map[string]bool{"Rax": true, "Rip": true})
+	text := strings.Join(lines, "\n")
+	if strings.Contains(text, "XMM0") {
+		t.Fatal("vector registers should not be shown")
+	}
+	if !strings.Contains(text, "[red]Rax") {
+		t.Fatal("changed register is not highlighted")
+	}
+	if strings.Contains(text, "[red]Rip") {
+		t.Fatal("Rip should not be highlighted")
+	}
+	if !strings.Contains(text, "0x246  [PF ZF IF IOPL=0]") {
+		t.Fatal(text)
+	}
+	// 3 个分组，每组一个标题
+	if len(lines) != 5+3 {
+		t.Fatal(text)
+	}
+}
+
+func TestVectorLanes(t *testing.T) {
+	value := "0x00000004000000030000000200000001\tv2_int={ 0000000200000001 0000000400000003 }\n\t[YMM0h] 0x3ff00000000000000000000000000000\tv2_int={ 0 0 }"
+	data := UI.ParseVectorRegister(value)
+	if len(data) != 32 {
+		t.Fatalf("got %d bytes", len(data))
+	}
+	if got := UI.FormatVectorLanes(data[:16], "i32"); got != "{1 2 3 4}" {
+		t.Fatal(got)
+	}
+	if got := UI.FormatVectorLanes(data[16:], "f64"); got != "{0 1}" {
+		t.Fatal(got)
+	}
+	if got := UI.FormatVectorLanes(data[:16], "hex"); got != "0x00000004000000030000000200000001" {
+		t.Fatal(got)
+	}
+}
+
+func TestPrintableString(t *testing.T) {
+	if s, ok := UI.PrintableString([]byte("hello\x00world")); !ok || s != `"hello"` {
+		t.Fatal(s)
+	}
+	if _, ok := UI.PrintableString([]byte{0x10, 0x20, 0x00, 0xc0, 0, 0, 0, 0}); ok {
+		t.Fatal("pointer should not be a string")
+	}
+}
+
+func TestFormatFrameLayout(t *testing.T) {
+	mems := make([]byte, 24)
+	mems[16] = 0x25
+	labels := map[uint64][]string{0x1010: {"return address"}, 0x1000: {"local x int"}}
+	boundaries := map[uint64]string{0x1000: "frame 0: main.f"}
+	lines := UI.FormatFrameLayout(mems, 0x1000, labels, boundaries)
+	if len(lines) != 4 {
+		t.Fatal(strings.Join(lines, "\n"))
+	}
+	if lines[0] != "[yellow]; ---- frame 0: main.f ----[white]" {
+		t.Fatal(lines[0])
+	}
+	if !strings.Contains(lines[3], "0x0000000000000025  [yellow]return address") {
+		t.Fatal(lines[3])
+	}
+}
+
+func TestRegisterBaseline(t *testing.T) {
+	baseline := &UI.RegisterBaseline{}
+	first := api.Registers{{Name: "Rax", Value: "0x1"}, {Name: "Rbx", Value: "0x2"}}
+	second := api.Registers{{Name: "Rax", Value: "0x3"}, {Name: "Rbx", Value: "0x2"}}
+	if changed := baseline.Changed(1, first); len(changed) != 0 {
+		t.Fatal(changed)
+	}
+	// 同一次停下刷新两次，仍然和上一次停下比较
+	for i := 0; i < 2; i++ {
+		if changed := baseline.Changed(2, second); len(changed) != 1 || !changed["Rax"] {
+			t.Fatalf("refresh %d: %v", i, changed)
+		}
+	}
+	if changed := baseline.Changed(3, second); len(changed) != 0 {
+		t.Fatal(changed)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"MyDebugger/src/api"
+	"testing"
+)
+
+func TestPackageOfFunction(t *testing.T) {
+	cases := map[string]string{
+		"main.main":                         "main",
+		"fmt.(*pp).doPrintln":               "fmt",
+		"net/http.(*Server).Serve":          "net/http",
+		"github.com/foo/bar.(*T).Method":    "github.com/foo/bar",
+		"github.com/foo/bar/baz.init.func1": "github.com/foo/bar/baz",
+		"runtime.gopark":                    "runtime",
+		"internal/poll.(*FD).Read":          "internal/poll",
+	}
+	for function, pkg := range cases {
+		if got := MyApi.PackageOfFunction(function); got != pkg {
+			t.Fatalf("%s: got %s, want %s", function, got, pkg)
+		}
+	}
+}
+
+func TestMatchSkipPattern(t *testing.T) {
+	cases := []struct {
+		pattern  string
+		function string
+		file     string
+		match    bool
+	}{
+		{MyApi.StdPattern, "fmt.Println", "/usr/local/go/src/fmt/print.go", true},
+		{MyApi.StdPattern, "runtime.mallocgc", "/usr/local/go/src/runtime/malloc.go", true},
+		{MyApi.StdPattern, "main.main", "/home/me/app/main.go", false},
+		// fmt 回调的 String 方法是用户代码，它里面的断点在自动跳出时仍然会让程序停下
+		{MyApi.StdPattern, "main.(*T).String", "/home/me/app/main.go", false},
+		{MyApi.StdPattern, "github.com/foo/bar.Run", "/home/me/bar/run.go", false},
+		// 没有 "." 的模块路径不是标准库
+		{MyApi.StdPattern, "MyDebugger/src/api.NewClient", "/home/me/MyDebugger/src/api/api.go", false},
+		{MyApi.StdPattern, "sample/worker.Run", "/home/me/sample/worker/run.go", false},
+		{MyApi.StdPattern, "internal/poll.(*FD).Read", "/usr/local/go/src/internal/poll/fd_unix.go", true},
+		{"github.com/foo/*", "github.com/foo/bar.Run", "/home/me/bar/run.go", true},
+		{"file:*_gen.go", "main.generated", "/home/me/app/types_gen.go", true},
+		{"file:/home/me/app/*.go", "main.main", "/home/me/app/main.go", true},
+		{"file:*_gen.go", "main.main", "/home/me/app/main.go", false},
+	}
+	for _, c := range cases {
+		if got := MyApi.MatchSkipPattern(c.pattern, c.function, c.file, "/usr/local/go"); got != c.match {
+			t.Fatalf("%s %s %s: got %v", c.pattern, c.function, c.file, got)
+		}
+	}
+}
+
+func TestGorootOfFile(t *testing.T) {
+	if got := MyApi.GorootOfFile("/usr/local/go/src/runtime/proc.go"); got != "/usr/local/go" {
+		t.Fatal(got)
+	}
+	// -trimpath 编译时没有 GOROOT
+	if got := MyApi.GorootOfFile("runtime/proc.go"); got != "" {
+		t.Fatal(got)
+	}
+	if MyApi.MatchSkipPattern(MyApi.StdPattern, "fmt.Println", "/usr/local/go/src/fmt/print.go", "") {
+		t.Fatal("std should not match without goroot")
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"MyDebugger/src/TUI/UI"
+	"testing"
+)
+
+func TestDiffMemory(t *testing.T) {
+	old := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	new := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 11}
+	lines := UI.DiffMemory(old, new, 0x100)
+	if len(lines) != 3 {
+		t.Fatal(lines)
+	}
+	if lines[0] != "0x100    01 02 03 04 05 06 07 08 09 [red]0b[white]" {
+		t.Fatal(lines[0])
+	}
+	if lines[2] != "0x108    0xa09 -> [red]0xb09[white]" {
+		t.Fatal(lines[2])
+	}
+	if lines = UI.DiffMemory(old, old, 0x100); lines[len(lines)-1] != "[yellow]; 没有变化[white]" {
+		t.Fatal(lines)
+	}
+}